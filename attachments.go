@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var attachmentRegex = regexp.MustCompile(`(?:^|\s)file:(\S+)`)
+
+// Extract file:/path references from text
+func extractAttachments(text string) []string {
+	matches := attachmentRegex.FindAllStringSubmatch(text, -1)
+	seen := make(map[string]bool)
+	var paths []string
+
+	for _, match := range matches {
+		if len(match) > 1 && !seen[match[1]] {
+			seen[match[1]] = true
+			paths = append(paths, match[1])
+		}
+	}
+
+	return paths
+}
+
+// Load the attachment paths of the given thoughts, keyed by thought id
+func attachmentsForThoughts(db *sql.DB, thoughts []Thought) (map[int64][]string, error) {
+	attachments := make(map[int64][]string)
+	if len(thoughts) == 0 {
+		return attachments, nil
+	}
+
+	placeholders := make([]string, len(thoughts))
+	args := make([]interface{}, len(thoughts))
+	for i, t := range thoughts {
+		placeholders[i] = "?"
+		args[i] = t.ID
+	}
+
+	rows, err := db.Query(`
+		SELECT thought_id, path
+		FROM attachments
+		WHERE thought_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY id ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query attachments: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var path string
+		if err := rows.Scan(&id, &path); err != nil {
+			return nil, fmt.Errorf("scan attachment: %w", err)
+		}
+		attachments[id] = append(attachments[id], path)
+	}
+
+	return attachments, rows.Err()
+}
+
+// Report whether an attached file still exists
+func attachmentExists(path string) bool {
+	_, err := os.Stat(expandHome(path, homeDir))
+	return err == nil
+}
+
+// Describe the attachments that no longer exist, if any
+func missingFilesNote(paths []string) string {
+	var missing []string
+	for _, p := range paths {
+		if !attachmentExists(p) {
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return ""
+	}
+	return colorize(colorRed, " [missing: "+strings.Join(missing, ", ")+"]")
+}
+
+// List the files referenced by a thought
+func listAttachments(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: prothought attachments <id>")
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid thought id: %s", args[0])
+	}
+
+	var exists bool
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM thoughts WHERE id = ?)", id).Scan(&exists); err != nil {
+		return fmt.Errorf("query thought: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("no thought with id %d", id)
+	}
+
+	attachments, err := attachmentsForThoughts(db, []Thought{{ID: id}})
+	if err != nil {
+		return err
+	}
+	if len(attachments[id]) == 0 {
+		fmt.Println("No attachments for that thought.")
+		return nil
+	}
+
+	for _, path := range attachments[id] {
+		status := ""
+		if !attachmentExists(path) {
+			status = " (missing)"
+		}
+		fmt.Printf("%s%s\n", path, status)
+	}
+
+	return nil
+}
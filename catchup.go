@@ -0,0 +1,110 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const catchupHeader = `# What did you do between %s and %s (%s without thoughts)?
+# Write it below and save; it is logged at %s.
+# Lines starting with "# " are ignored. Save an empty file to log nothing.
+`
+
+// Find the longest stretch between consecutive thoughts, counting the time
+// since the last one when the period reaches up to now
+func largestGap(thoughts []Thought, end, now time.Time) (time.Time, time.Time) {
+	var from, to time.Time
+	var prev time.Time
+	for i, t := range thoughts {
+		ts, err := time.ParseInLocation(timestampFormat, wholeSeconds(t.Timestamp), time.Local)
+		if err != nil {
+			continue
+		}
+		if i > 0 && ts.Sub(prev) > to.Sub(from) {
+			from, to = prev, ts
+		}
+		prev = ts
+	}
+	if !prev.IsZero() && !end.Before(now) && now.Sub(prev) > to.Sub(from) {
+		from, to = prev, now
+	}
+	return from, to
+}
+
+// Format a gap as hours and minutes, e.g. 5h30m
+func formatGap(d time.Duration) string {
+	d = d.Round(time.Minute)
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%dm", m)
+	case m == 0:
+		return fmt.Sprintf("%dh", h)
+	}
+	return fmt.Sprintf("%dh%dm", h, m)
+}
+
+// Find the largest gap without thoughts in a period, today by default, and
+// open the editor to fill it in, logging the result in the middle of the gap.
+// Gaps shorter than --min-gap are left alone.
+func catchUp(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	minGap := fs.Duration("min-gap", 3*time.Hour, "skip gaps shorter than this")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *minGap < 0 {
+		return fmt.Errorf("--min-gap cannot be negative")
+	}
+	if len(rest) == 0 {
+		rest = []string{"today"}
+	}
+
+	q, err := periodQuery(rest)
+	if err != nil {
+		return err
+	}
+	thoughts, err := queryThoughts(db, q)
+	if err != nil {
+		return err
+	}
+	if len(thoughts) == 0 {
+		fmt.Println("No thoughts found for that period.")
+		return nil
+	}
+
+	end, _ := time.ParseInLocation(timestampFormat, q.end, time.Local)
+	from, to := largestGap(thoughts, end, time.Now())
+	gap := to.Sub(from)
+	if gap < *minGap {
+		fmt.Printf("No gap of %s or more to catch up on (largest is %s).\n", formatGap(*minGap), formatGap(gap))
+		return nil
+	}
+
+	at := from.Add(gap / 2).Format(timestampFormat)
+	content, err := editText(fmt.Sprintf(catchupHeader,
+		from.Format(timestampFormat), to.Format(timestampFormat), formatGap(gap), at))
+	if err != nil {
+		return err
+	}
+
+	// Only drop comment lines; a line may well start with a hashtag
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		if line != "#" && !strings.HasPrefix(line, "# ") {
+			lines = append(lines, line)
+		}
+	}
+	text := strings.TrimSpace(strings.Join(lines, "\n"))
+	if text == "" {
+		fmt.Println("Nothing to log.")
+		return nil
+	}
+	return logThought(db, text, addOptions{at: at})
+}
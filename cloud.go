@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	colorBold = "\033[1m"
+	// Number of sizes markers are scaled into
+	cloudWeights = 4
+	// Line width when not writing to a terminal
+	cloudWidth = 72
+)
+
+// cloudMarker is a marker with its count and size in the cloud
+type cloudMarker struct {
+	Marker string `json:"marker"`
+	Count  int    `json:"count"`
+	Weight int    `json:"weight"`
+}
+
+// Scale marker counts into weights from 1 to cloudWeights on a log scale,
+// so a single dominant marker doesn't flatten all the others
+func cloudWeightsFor(counts []markerCount) []cloudMarker {
+	lo, hi := math.MaxInt, 0
+	for _, mc := range counts {
+		lo, hi = min(lo, mc.Count), max(hi, mc.Count)
+	}
+	markers := make([]cloudMarker, 0, len(counts))
+	for _, mc := range counts {
+		weight := 2
+		if hi > lo {
+			frac := (math.Log(float64(mc.Count)) - math.Log(float64(lo))) / (math.Log(float64(hi)) - math.Log(float64(lo)))
+			weight = 1 + int(math.Round(frac*float64(cloudWeights-1)))
+		}
+		markers = append(markers, cloudMarker{Marker: mc.Marker, Count: mc.Count, Weight: weight})
+	}
+	return markers
+}
+
+// Render a marker at its weight. Sizes stay distinguishable without color:
+// the heaviest are bracketed and upper-cased.
+func cloudWord(m cloudMarker) (plain, styled string) {
+	switch m.Weight {
+	case 4:
+		plain = "[ #" + strings.ToUpper(m.Marker) + " ]"
+		return plain, colorize(colorBold+colorCyan, plain)
+	case 3:
+		plain = "#" + strings.ToUpper(m.Marker)
+		return plain, colorize(colorBold, plain)
+	case 2:
+		plain = "#" + m.Marker
+		return plain, plain
+	default:
+		plain = "#" + m.Marker
+		return plain, colorize(colorDim, plain)
+	}
+}
+
+// Print the markers of a period as a tag cloud, sized by how many thoughts
+// carry them
+func showCloud(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "print markers with counts and weights as a JSON array")
+	periodArgs, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	q, err := periodQuery(periodArgs)
+	if err != nil {
+		return err
+	}
+	counts, err := countMarkersForQuery(db, q)
+	if err != nil {
+		return err
+	}
+	markers := cloudWeightsFor(counts)
+	// Alphabetical, so the big ones are scattered like in a cloud
+	sort.Slice(markers, func(i, j int) bool { return markers[i].Marker < markers[j].Marker })
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(markers); err != nil {
+			return fmt.Errorf("encode json: %w", err)
+		}
+		return nil
+	}
+
+	if len(markers) == 0 {
+		fmt.Println("No markers for that period.")
+		return nil
+	}
+
+	width := wrapWidth()
+	if width == 0 {
+		width = cloudWidth
+	}
+	var line strings.Builder
+	lineLen := 0
+	for _, m := range markers {
+		plain, styled := cloudWord(m)
+		n := utf8.RuneCountInString(plain)
+		if lineLen > 0 && lineLen+2+n > width {
+			fmt.Println(line.String())
+			line.Reset()
+			lineLen = 0
+		}
+		if lineLen > 0 {
+			line.WriteString("  ")
+			lineLen += 2
+		}
+		line.WriteString(styled)
+		lineLen += n
+	}
+	fmt.Println(line.String())
+	return nil
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+)
+
+const (
+	colorReset = "\033[0m"
+	colorDim   = "\033[2m"
+	colorCyan  = "\033[36m"
+	colorRed   = "\033[31m"
+	colorGreen = "\033[32m"
+	// Strikethrough, for words removed in a diff
+	colorStrike = "\033[9m"
+)
+
+// Report whether output should be colored according to the color setting
+func useColor() bool {
+	if cfg.enabled("plain") {
+		return false
+	}
+	switch cfg.get("color") {
+	case "always":
+		return true
+	case "never":
+		return false
+	}
+	return os.Getenv("NO_COLOR") == "" && isTerminal(os.Stdout)
+}
+
+// Wrap text in an ANSI color when coloring is enabled
+func colorize(color, text string) string {
+	if !useColor() {
+		return text
+	}
+	return color + text + colorReset
+}
+
+// Color the hashtags inside a thought's text
+func highlightHashtags(text string) string {
+	if !useColor() {
+		return text
+	}
+	return hashtagRegex.ReplaceAllString(text, colorCyan+"$0"+colorReset)
+}
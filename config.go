@@ -0,0 +1,506 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// setting describes a user preference and where it can be set from
+type setting struct {
+	key      string
+	env      string
+	flag     string
+	def      func() string
+	validate func(string) error
+	boolean  bool // the flag may be given without a value
+}
+
+// config holds the effective value of every setting and its source
+type config struct {
+	values  map[string]string
+	sources map[string]string
+}
+
+var (
+	configPath string
+	cfg        *config
+
+	settings = []setting{
+		{
+			key:  "db_path",
+			env:  "PROTHOUGHT_DB",
+			flag: "db",
+			def:  func() string { return defaultDBPath },
+			validate: func(v string) error {
+				if strings.TrimSpace(v) == "" {
+					return fmt.Errorf("db_path cannot be empty")
+				}
+				return nil
+			},
+		},
+		{
+			key: "default_period",
+			env: "PROTHOUGHT_DEFAULT_PERIOD",
+			def: func() string { return "today" },
+			validate: func(v string) error {
+				_, err := periodQuery([]string{v})
+				return err
+			},
+		},
+		{
+			key:  "timezone",
+			env:  "PROTHOUGHT_TZ",
+			flag: "tz",
+			def:  func() string { return "" },
+			validate: func(v string) error {
+				if v == "" {
+					return nil
+				}
+				if _, err := time.LoadLocation(v); err != nil {
+					return fmt.Errorf("unknown time zone %q; use an IANA name such as America/New_York", v)
+				}
+				return nil
+			},
+		},
+		{
+			key: "time_format",
+			env: "PROTHOUGHT_TIME_FORMAT",
+			def: func() string { return timestampFormat },
+			validate: func(v string) error {
+				// A layout without any reference-time elements formats to itself
+				if v == "" || time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC).Format(v) == v {
+					return fmt.Errorf("time_format must be a Go time layout such as %q", timestampFormat)
+				}
+				return nil
+			},
+		},
+		{
+			key:      "timestamp_precision",
+			env:      "PROTHOUGHT_TIMESTAMP_PRECISION",
+			def:      func() string { return "s" },
+			validate: oneOf("s", "ms", "us", "ns"),
+		},
+		{
+			key:      "relative_time",
+			env:      "PROTHOUGHT_RELATIVE_TIME",
+			flag:     "relative",
+			def:      func() string { return "false" },
+			validate: isBool,
+			boolean:  true,
+		},
+		{
+			key:      "color",
+			env:      "PROTHOUGHT_COLOR",
+			def:      func() string { return "auto" },
+			validate: oneOf("auto", "always", "never"),
+		},
+		{
+			key:      "plain",
+			env:      "PROTHOUGHT_PLAIN",
+			flag:     "plain",
+			def:      func() string { return "false" },
+			validate: isBool,
+			boolean:  true,
+		},
+		{
+			key:      "case_sensitive",
+			env:      "PROTHOUGHT_CASE_SENSITIVE",
+			def:      func() string { return "false" },
+			validate: isBool,
+		},
+		{
+			key:      "strip_diacritics",
+			env:      "PROTHOUGHT_STRIP_DIACRITICS",
+			def:      func() string { return "false" },
+			validate: isBool,
+		},
+		{
+			key: "strike_format",
+			env: "PROTHOUGHT_STRIKE_FORMAT",
+			def: func() string { return "~~%s~~" },
+			validate: func(v string) error {
+				if strings.Count(v, "%s") != 1 || strings.TrimSpace(v) == "%s" {
+					return fmt.Errorf("strike_format must contain %%s once with text around it, such as ~~%%s~~ or [nvm] %%s")
+				}
+				return nil
+			},
+		},
+		{
+			key:      "emoji",
+			env:      "PROTHOUGHT_EMOJI",
+			def:      func() string { return "false" },
+			validate: isBool,
+		},
+		{
+			key: "emoji_map",
+			env: "PROTHOUGHT_EMOJI_MAP",
+			def: func() string { return "" },
+			validate: func(v string) error {
+				_, err := parseEmojiMap(v)
+				return err
+			},
+		},
+		{
+			key: "warn_size",
+			env: "PROTHOUGHT_WARN_SIZE",
+			def: func() string { return "10KB" },
+			validate: func(v string) error {
+				_, err := parseSize(v)
+				return err
+			},
+		},
+		{
+			key: "confirm_threshold",
+			env: "PROTHOUGHT_CONFIRM_THRESHOLD",
+			def: func() string { return "0" },
+			validate: func(v string) error {
+				if n, err := strconv.Atoi(v); err != nil || n < 0 {
+					return fmt.Errorf("confirm_threshold must be a number of thoughts, 0 or more")
+				}
+				return nil
+			},
+		},
+		{
+			key: "protected_markers",
+			env: "PROTHOUGHT_PROTECTED_MARKERS",
+			def: func() string { return "" },
+			validate: func(v string) error {
+				for _, m := range strings.Split(v, ",") {
+					m = strings.TrimPrefix(strings.TrimSpace(m), "#")
+					if m != "" && !markerNameRegex.MatchString(m) {
+						return fmt.Errorf("protected_markers must be markers separated by commas, got %q", m)
+					}
+				}
+				return nil
+			},
+		},
+		{
+			key: "default_marker",
+			env: "PROTHOUGHT_DEFAULT_MARKER",
+			def: func() string { return "" },
+			validate: func(v string) error {
+				tag := "#" + strings.TrimPrefix(v, "#")
+				if v != "" && hashtagRegex.FindString(tag) != tag {
+					return fmt.Errorf("default_marker must be a single marker such as #project or #sprint=24")
+				}
+				return nil
+			},
+		},
+		{
+			key:      "git_context",
+			env:      "PROTHOUGHT_GIT_CONTEXT",
+			def:      func() string { return "false" },
+			validate: isBool,
+		},
+		{
+			key:      "lock",
+			env:      "PROTHOUGHT_LOCK",
+			flag:     "lock",
+			def:      func() string { return "false" },
+			validate: isBool,
+			boolean:  true,
+		},
+		{
+			key:      "max_open_conns",
+			env:      "PROTHOUGHT_MAX_OPEN_CONNS",
+			flag:     "max-open-conns",
+			def:      func() string { return "1" },
+			validate: isCount,
+		},
+		{
+			key:      "max_idle_conns",
+			env:      "PROTHOUGHT_MAX_IDLE_CONNS",
+			flag:     "max-idle-conns",
+			def:      func() string { return "1" },
+			validate: isCount,
+		},
+		{
+			key: "lint_conflicts",
+			env: "PROTHOUGHT_LINT_CONFLICTS",
+			def: func() string { return "todo:done" },
+			validate: func(v string) error {
+				_, err := parseMarkerPairs(v)
+				return err
+			},
+		},
+		{
+			key: "webhook_url",
+			env: "PROTHOUGHT_WEBHOOK_URL",
+			def: func() string { return "" },
+			validate: func(v string) error {
+				if v != "" && !strings.HasPrefix(v, "http://") && !strings.HasPrefix(v, "https://") {
+					return fmt.Errorf("webhook_url must be an http:// or https:// URL")
+				}
+				return nil
+			},
+		},
+		{
+			key: "log_file",
+			env: "PROTHOUGHT_LOG",
+			def: func() string { return "" },
+		},
+		{
+			key: "last_export",
+			env: "PROTHOUGHT_LAST_EXPORT",
+			def: func() string { return "" },
+			validate: func(v string) error {
+				if v == "" {
+					return nil
+				}
+				if _, err := time.ParseInLocation(timestampFormat, v, time.Local); err != nil {
+					return fmt.Errorf("last_export must be a timestamp such as %s", timestampFormat)
+				}
+				return nil
+			},
+		},
+	}
+)
+
+// Build a validator accepting only the given values
+func oneOf(allowed ...string) func(string) error {
+	return func(v string) error {
+		for _, a := range allowed {
+			if v == a {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of: %s", strings.Join(allowed, ", "))
+	}
+}
+
+// Validate a boolean setting
+func isBool(v string) error {
+	if _, err := strconv.ParseBool(v); err != nil {
+		return fmt.Errorf("must be true or false")
+	}
+	return nil
+}
+
+// Validate a setting that counts something
+func isCount(v string) error {
+	if n, err := strconv.Atoi(v); err != nil || n < 0 {
+		return fmt.Errorf("must be a whole number, 0 or more")
+	}
+	return nil
+}
+
+// Look up a setting by key
+func findSetting(key string) (setting, bool) {
+	for _, s := range settings {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+// Read key = value pairs from the config file. A missing file is empty.
+func readConfigFile(path string) (map[string]string, error) {
+	values := make(map[string]string)
+
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return values, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open config file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("%s:%d: expected key = value", path, lineNo)
+		}
+		values[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read config file: %w", err)
+	}
+
+	return values, nil
+}
+
+// Resolve every setting from flags, environment, config file and defaults,
+// in that order of precedence
+func loadConfig(flags map[string]string) (*config, error) {
+	file, err := readConfigFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &config{values: make(map[string]string), sources: make(map[string]string)}
+	for _, s := range settings {
+		value, source := s.def(), "default"
+		if v, ok := file[s.key]; ok {
+			value, source = v, "file"
+		}
+		if v := os.Getenv(s.env); v != "" {
+			value, source = v, "env"
+		}
+		if v, ok := flags[s.flag]; ok && s.flag != "" {
+			value, source = v, "flag"
+		}
+
+		if s.validate != nil {
+			if err := s.validate(value); err != nil {
+				return nil, fmt.Errorf("invalid %s from %s: %w", s.key, source, err)
+			}
+		}
+		c.values[s.key] = value
+		c.sources[s.key] = source
+	}
+
+	return c, nil
+}
+
+// Get the effective value of a setting
+func (c *config) get(key string) string {
+	return c.values[key]
+}
+
+// Report whether a boolean setting is enabled
+func (c *config) enabled(key string) bool {
+	v, _ := strconv.ParseBool(c.values[key])
+	return v
+}
+
+// Extract global flags (such as --db) that precede the command
+func parseGlobalFlags(args []string) (map[string]string, []string, error) {
+	flags := make(map[string]string)
+
+	for len(args) > 0 && strings.HasPrefix(args[0], "-") {
+		switch args[0] {
+		case "-v", "--verbose":
+			verbosity++
+			args = args[1:]
+			continue
+		case "-vv":
+			verbosity += 2
+			args = args[1:]
+			continue
+		}
+		if !strings.HasPrefix(args[0], "--") {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimPrefix(args[0], "--"), "=")
+
+		var match *setting
+		for i := range settings {
+			if settings[i].flag != "" && settings[i].flag == name {
+				match = &settings[i]
+				break
+			}
+		}
+		if match == nil {
+			break
+		}
+
+		args = args[1:]
+		if !hasValue && match.boolean {
+			value = "true"
+		} else if !hasValue {
+			if len(args) == 0 {
+				return nil, nil, fmt.Errorf("flag --%s needs a value", name)
+			}
+			value, args = args[0], args[1:]
+		}
+		flags[name] = value
+	}
+
+	return flags, args, nil
+}
+
+// Write a single setting to the config file, keeping other lines intact
+func writeConfigValue(path, key, value string) error {
+	content, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read config file: %w", err)
+	}
+
+	var lines []string
+	if len(content) > 0 {
+		lines = strings.Split(strings.TrimRight(string(content), "\n"), "\n")
+	}
+
+	replaced := false
+	entry := fmt.Sprintf("%s = %s", key, value)
+	for i, line := range lines {
+		k, _, ok := strings.Cut(line, "=")
+		if ok && strings.TrimSpace(k) == key && !strings.HasPrefix(strings.TrimSpace(line), "#") {
+			lines[i] = entry
+			replaced = true
+		}
+	}
+	if !replaced {
+		lines = append(lines, entry)
+	}
+
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		return fmt.Errorf("write config file: %w", err)
+	}
+	return nil
+}
+
+// Handle the config get/set/list subcommands
+func runConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: prothought config get <key> | set <key> <value> | list")
+	}
+
+	switch args[0] {
+	case "get":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: prothought config get <key>")
+		}
+		if _, ok := findSetting(args[1]); !ok {
+			return fmt.Errorf("unknown config key: %s", args[1])
+		}
+		fmt.Println(cfg.get(args[1]))
+
+	case "set":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: prothought config set <key> <value>")
+		}
+		s, ok := findSetting(args[1])
+		if !ok {
+			return fmt.Errorf("unknown config key: %s", args[1])
+		}
+		if s.validate != nil {
+			if err := s.validate(args[2]); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", s.key, err)
+			}
+		}
+		if err := writeConfigValue(configPath, s.key, args[2]); err != nil {
+			return err
+		}
+		fmt.Printf("Set %s = %s in %s\n", s.key, args[2], configPath)
+
+	case "list":
+		keys := make([]string, 0, len(settings))
+		for _, s := range settings {
+			keys = append(keys, s.key)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("%-16s = %s (%s)\n", k, cfg.get(k), cfg.sources[k])
+		}
+
+	default:
+		return fmt.Errorf("unknown config subcommand: %s", args[0])
+	}
+
+	return nil
+}
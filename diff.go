@@ -0,0 +1,83 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// Count markers for the thoughts of a single period
+func markerCountsForPeriod(db *sql.DB, period string) (map[string]int, error) {
+	thoughts, err := thoughtsForPeriod(db, []string{period}, "")
+	if err != nil {
+		return nil, err
+	}
+	markers, err := markersForThoughts(db, thoughts)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, mc := range countMarkers(thoughts, markers) {
+		counts[mc.Marker] = mc.Count
+	}
+	return counts, nil
+}
+
+// Compare marker usage between two days (or any two periods)
+func diffPeriods(db *sql.DB, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: prothought diff <date1> <date2>")
+	}
+	before, err := markerCountsForPeriod(db, args[0])
+	if err != nil {
+		return err
+	}
+	after, err := markerCountsForPeriod(db, args[1])
+	if err != nil {
+		return err
+	}
+
+	var added, removed, changed []string
+	for m := range after {
+		if _, ok := before[m]; !ok {
+			added = append(added, m)
+		} else if before[m] != after[m] {
+			changed = append(changed, m)
+		}
+	}
+	for m := range before {
+		if _, ok := after[m]; !ok {
+			removed = append(removed, m)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	sort.Strings(changed)
+
+	fmt.Printf("Markers %s -> %s\n", args[0], args[1])
+	if len(added)+len(removed)+len(changed) == 0 {
+		fmt.Println("\nNo differences in marker usage.")
+		return nil
+	}
+
+	if len(added) > 0 {
+		fmt.Printf("\nAdded (only %s):\n", args[1])
+		for _, m := range added {
+			fmt.Printf("  #%-20s %d\n", m, after[m])
+		}
+	}
+	if len(removed) > 0 {
+		fmt.Printf("\nRemoved (only %s):\n", args[0])
+		for _, m := range removed {
+			fmt.Printf("  #%-20s %d\n", m, before[m])
+		}
+	}
+	if len(changed) > 0 {
+		fmt.Println("\nChanged:")
+		for _, m := range changed {
+			fmt.Printf("  #%-20s %d -> %d (%+d)\n", m, before[m], after[m], after[m]-before[m])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// markerCount pairs a marker with the number of thoughts carrying it
+type markerCount struct {
+	Marker string
+	Count  int
+}
+
+// Count how many thoughts carry each marker, most used first
+func countMarkers(thoughts []Thought, markers map[int64][]string) []markerCount {
+	counts := make(map[string]int)
+	for _, t := range thoughts {
+		for _, m := range markers[t.ID] {
+			counts[m]++
+		}
+	}
+
+	result := make([]markerCount, 0, len(counts))
+	for m, c := range counts {
+		result = append(result, markerCount{Marker: m, Count: c})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Marker < result[j].Marker
+	})
+
+	return result
+}
+
+// Print a weekly review: thoughts per day, top markers and the thoughts
+// that were kept, grouped by marker
+func showDigest(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "text", "output format: text or md")
+	periodArgs, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "md" {
+		return fmt.Errorf("unsupported digest format: %s", *format)
+	}
+
+	if len(periodArgs) == 0 || periodArgs[0] == "week" {
+		periodArgs = []string{"lastweek"}
+	}
+	startTS, endTS, err := parsePeriod(periodArgs)
+	if err != nil {
+		return err
+	}
+
+	thoughts, err := thoughtsForPeriod(db, periodArgs, "")
+	if err != nil {
+		return err
+	}
+	markers, err := markersForThoughts(db, thoughts)
+	if err != nil {
+		return err
+	}
+
+	// Thoughts per day, including days without any
+	perDay := make(map[string]int)
+	for _, t := range thoughts {
+		perDay[t.Timestamp[:10]]++
+	}
+	start, _ := time.ParseInLocation(timestampFormat, startTS, time.Local)
+	end, _ := time.ParseInLocation(timestampFormat, endTS, time.Local)
+	var days []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		days = append(days, d.Format("2006-01-02"))
+	}
+
+	top := countMarkers(thoughts, markers)
+	if len(top) > 10 {
+		top = top[:10]
+	}
+
+	// Kept thoughts grouped by marker; a thought appears under each of its markers
+	groups := make(map[string][]Thought)
+	var groupOrder []string
+	for _, t := range thoughts {
+		if isStruck(t.Text) {
+			continue
+		}
+		tags := markers[t.ID]
+		if len(tags) == 0 {
+			tags = []string{""}
+		}
+		for _, m := range tags {
+			if _, ok := groups[m]; !ok {
+				groupOrder = append(groupOrder, m)
+			}
+			groups[m] = append(groups[m], t)
+		}
+	}
+	sort.SliceStable(groupOrder, func(i, j int) bool {
+		// Untagged thoughts go last
+		if groupOrder[i] == "" || groupOrder[j] == "" {
+			return groupOrder[j] == ""
+		}
+		return groupOrder[i] < groupOrder[j]
+	})
+
+	title := fmt.Sprintf("Digest %s .. %s", start.Format("2006-01-02"), end.Format("2006-01-02"))
+	groupName := func(m string) string {
+		if m == "" {
+			return "untagged"
+		}
+		return "#" + m
+	}
+
+	if *format == "md" {
+		fmt.Printf("# %s\n\n", title)
+		fmt.Println("## Thoughts per day")
+		fmt.Println()
+		fmt.Println("| Day | Thoughts |")
+		fmt.Println("|-----|----------|")
+		for _, d := range days {
+			fmt.Printf("| %s | %d |\n", d, perDay[d])
+		}
+		fmt.Printf("\n**Total:** %d\n\n", len(thoughts))
+
+		fmt.Println("## Top markers")
+		fmt.Println()
+		if len(top) == 0 {
+			fmt.Println("_No markers used._")
+		}
+		for _, mc := range top {
+			fmt.Printf("- #%s (%d)\n", mc.Marker, mc.Count)
+		}
+
+		fmt.Println()
+		fmt.Println("## Thoughts by marker")
+		for _, m := range groupOrder {
+			fmt.Printf("\n### %s\n\n", groupName(m))
+			for _, t := range groups[m] {
+				fmt.Printf("- %s _(%s)_\n", t.Text, displayTime(t.Timestamp))
+			}
+		}
+		return nil
+	}
+
+	fmt.Println(title)
+	fmt.Println()
+	fmt.Println("Thoughts per day:")
+	for _, d := range days {
+		fmt.Printf("  %s  %d\n", d, perDay[d])
+	}
+	fmt.Printf("  %-10s  %d\n", "total", len(thoughts))
+
+	fmt.Println()
+	fmt.Println("Top markers:")
+	if len(top) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, mc := range top {
+		fmt.Printf("  #%-20s %d\n", mc.Marker, mc.Count)
+	}
+
+	fmt.Println()
+	fmt.Println("Thoughts by marker:")
+	if len(groupOrder) == 0 {
+		fmt.Println("  (none)")
+	}
+	for _, m := range groupOrder {
+		fmt.Printf("  %s\n", groupName(m))
+		for _, t := range groups[m] {
+			fmt.Printf("    [%s] %s\n", displayTime(t.Timestamp), strings.TrimSpace(t.Text))
+		}
+	}
+
+	return nil
+}
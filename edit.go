@@ -0,0 +1,139 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Replace a thought's text and re-extract its markers and attachments
+func updateThought(db *sql.DB, id int64, text string) ([]string, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	hashtags, err := updateThoughtTx(tx, id, text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return hashtags, nil
+}
+
+// Replace a thought's text within a transaction, keeping the old text in
+// its history, and return its new markers. Hashtags dropped from the text
+// lose their marker; markers that were never in the text stay.
+func updateThoughtTx(tx *sql.Tx, id int64, text string) ([]string, error) {
+	var current string
+	if err := tx.QueryRow("SELECT text FROM thoughts WHERE id = ?", id).Scan(&current); err != nil {
+		return nil, fmt.Errorf("query thought: %w", err)
+	}
+	stored, err := thoughtMarkersTx(tx, id)
+	if err != nil {
+		return nil, err
+	}
+	kept := markersNotInText(stored, extractHashtags(current))
+
+	if err := recordHistoryTx(tx, id); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec("UPDATE thoughts SET text = ? WHERE id = ?", text, id); err != nil {
+		return nil, fmt.Errorf("update thought: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM markers WHERE thought_id = ?", id); err != nil {
+		return nil, fmt.Errorf("delete markers: %w", err)
+	}
+	if _, err := tx.Exec("DELETE FROM attachments WHERE thought_id = ?", id); err != nil {
+		return nil, fmt.Errorf("delete attachments: %w", err)
+	}
+
+	hashtags := appendMissingMarkers(extractHashtags(text), kept)
+	if err := insertMarkers(tx, id, hashtags); err != nil {
+		return nil, err
+	}
+	if err := insertAttachments(tx, id, extractAttachments(text)); err != nil {
+		return nil, err
+	}
+	return hashtags, nil
+}
+
+// Edit a thought, taking the new text from the arguments or, when there
+// are none, from the user's editor
+func editThought(db *sql.DB, id int64, words []string) error {
+	var current string
+	var deletedAt sql.NullString
+	err := db.QueryRow("SELECT text, deleted_at FROM thoughts WHERE id = ?", id).Scan(&current, &deletedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no thought with id %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("query thought: %w", err)
+	}
+	if deletedAt.Valid {
+		return fmt.Errorf("thought %d is in the trash; restore it first", id)
+	}
+
+	text := strings.TrimSpace(strings.Join(words, " "))
+	if len(words) == 0 {
+		edited, err := editText(current)
+		if err != nil {
+			return err
+		}
+		text = strings.TrimSpace(edited)
+	}
+	if text == "" {
+		return fmt.Errorf("thought text cannot be empty")
+	}
+	if text == current {
+		fmt.Printf("Thought %d is unchanged.\n", id)
+		return nil
+	}
+
+	hashtags, err := updateThought(db, id, text)
+	if err != nil {
+		return err
+	}
+
+	markerInfo := ""
+	if len(hashtags) > 0 {
+		markerInfo = " with markers: " + joinMarkers(hashtags)
+	}
+	fmt.Printf("Updated thought %d%s\n", id, markerInfo)
+	fmt.Printf("  %s\n", formatWordDiff(diffWords(current, text)))
+	return nil
+}
+
+// Edit a thought by id: edit <id> [new text...]
+func editThoughtByID(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: prothought edit <id> [new text...]")
+	}
+	id, err := parseThoughtID(args[0])
+	if err != nil {
+		return err
+	}
+	return editThought(db, id, args[1:])
+}
+
+// Edit the most recent thought: edit-last [new text...]
+func editLastThought(db *sql.DB, args []string) error {
+	var id int64
+	err := db.QueryRow(`
+		SELECT id
+		FROM thoughts
+		WHERE deleted_at IS NULL
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1`).Scan(&id)
+	if err == sql.ErrNoRows {
+		fmt.Println("No thoughts to edit.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("query last thought: %w", err)
+	}
+	return editThought(db, id, args)
+}
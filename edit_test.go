@@ -0,0 +1,50 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEditAndReindexKeepMarkersNotInText(t *testing.T) {
+	db := newTestDB(t)
+	setTestSetting(t, "PROTHOUGHT_DEFAULT_MARKER", "checkout")
+
+	mustLog(t, db, "Cart totals are off #bug #cents")
+	captureStdout(t, func() {
+		if err := tagPeriod(db, "tag-period", []string{"today", "#reviewed", "--yes"}); err != nil {
+			t.Fatalf("tag-period: %v", err)
+		}
+	})
+
+	// The hashtag dropped from the text loses its marker; the rest stay
+	if _, err := updateThought(db, 1, "Cart totals are off by a cent #bug"); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	want := "bug,checkout,reviewed"
+	if got := strings.Join(storedMarkers(t, db, 1), ","); got != want {
+		t.Errorf("markers after edit = %q, want %q", got, want)
+	}
+
+	var err error
+	captureStdout(t, func() { err = logThought(db, "Deferred note #later", addOptions{deferMarkers: true}) })
+	if err != nil {
+		t.Fatalf("log deferred: %v", err)
+	}
+	if got := strings.Join(storedMarkers(t, db, 2), ","); got != "checkout" {
+		t.Errorf("markers of deferred thought = %q, want only the default marker", got)
+	}
+
+	out := captureStdout(t, func() { err = reindexMarkers(db) })
+	if err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+	if !strings.Contains(out, "keeping 3 not in the text") {
+		t.Errorf("reindex output = %q, want 3 markers kept", out)
+	}
+	if got := strings.Join(storedMarkers(t, db, 1), ","); got != want {
+		t.Errorf("markers after reindex = %q, want %q", got, want)
+	}
+	if got := strings.Join(storedMarkers(t, db, 2), ","); got != "later,checkout" {
+		t.Errorf("markers of deferred thought after reindex = %q, want later,checkout", got)
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const editPeriodHeader = `# Edit the thoughts below and save to apply the changes.
+# Keep the [id] at the start of each line. Delete a line to move that
+# thought to the trash. Newlines within a thought are written as \n.
+# Lines starting with # are ignored.
+`
+
+// editLineRegex matches "[id] text" lines in an edit-period file
+var editLineRegex = regexp.MustCompile(`^\[(\d+)\] ?(.*)$`)
+
+// Escape a thought so it fits on a single line
+func escapeEditLine(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(text)
+}
+
+// Reverse escapeEditLine
+func unescapeEditLine(line string) string {
+	var b strings.Builder
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' && i+1 < len(line) {
+			switch line[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(line[i])
+	}
+	return b.String()
+}
+
+// Parse an edited file into new text by thought id. Every id must be one
+// of the thoughts that were written out, and appear at most once.
+func parseEditedThoughts(content string, known map[int64]string) (map[int64]string, error) {
+	edited := make(map[int64]string)
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m := editLineRegex.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: expected \"[id] text\"", lineNo)
+		}
+		id, _ := strconv.ParseInt(m[1], 10, 64)
+		if _, ok := known[id]; !ok {
+			return nil, fmt.Errorf("line %d: thought %d was not part of this edit", lineNo, id)
+		}
+		if _, ok := edited[id]; ok {
+			return nil, fmt.Errorf("line %d: thought %d appears more than once", lineNo, id)
+		}
+		text := strings.TrimSpace(unescapeEditLine(m[2]))
+		if text == "" {
+			return nil, fmt.Errorf("line %d: thought %d has no text; delete the line to remove it", lineNo, id)
+		}
+		edited[id] = text
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read edited file: %w", err)
+	}
+	return edited, nil
+}
+
+// Bulk-edit a period's thoughts in the user's editor
+func editPeriod(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	yes := fs.Bool("yes", false, "apply deletions without asking")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	periodArgs, marker := parseArgsWithMarker(rest)
+
+	thoughts, err := thoughtsForPeriod(db, periodArgs, marker)
+	if err != nil {
+		return err
+	}
+	if len(thoughts) == 0 {
+		fmt.Println("No thoughts found for that period.")
+		return nil
+	}
+
+	var b strings.Builder
+	b.WriteString(editPeriodHeader)
+	known := make(map[int64]string)
+	for _, t := range thoughts {
+		known[t.ID] = t.Text
+		fmt.Fprintf(&b, "[%d] %s\n", t.ID, escapeEditLine(t.Text))
+	}
+
+	content, err := editText(b.String())
+	if err != nil {
+		return err
+	}
+	edited, err := parseEditedThoughts(content, known)
+	if err != nil {
+		return fmt.Errorf("nothing was changed: %w", err)
+	}
+
+	var updates, deletions []Thought
+	for _, t := range thoughts {
+		text, ok := edited[t.ID]
+		if !ok {
+			deletions = append(deletions, t)
+		} else if text != t.Text {
+			updates = append(updates, Thought{ID: t.ID, Timestamp: t.Timestamp, Text: text})
+		}
+	}
+	if len(updates) == 0 && len(deletions) == 0 {
+		fmt.Println("No changes.")
+		return nil
+	}
+
+	if len(deletions) > 0 {
+		if needsConfirm(len(deletions), *yes) && isTerminal(os.Stdin) {
+			for _, t := range deletions {
+				fmt.Printf("  delete %s\n", formatThought(t))
+			}
+		}
+		action := fmt.Sprintf("move %d thought(s) to the trash and update %d", len(deletions), len(updates))
+		ok, err := confirmBulk(len(deletions), action, *yes)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+
+		ids := make([]int64, len(deletions))
+		for i, t := range deletions {
+			ids[i] = t.ID
+		}
+		cond, condArgs := idsCondition(ids)
+		ok, err = confirmProtected(db, "move to the trash", cond, condArgs...)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range updates {
+		if _, err := updateThoughtTx(tx, t.ID, t.Text); err != nil {
+			return err
+		}
+	}
+	now := time.Now().Format(timestampFormat)
+	for _, t := range deletions {
+		if _, err := tx.Exec("UPDATE thoughts SET deleted_at = ? WHERE id = ?", now, t.ID); err != nil {
+			return fmt.Errorf("update thought: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Printf("Updated %d thought(s), moved %d to the trash.\n", len(updates), len(deletions))
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// shortcodeRegex matches :name: style emoji shortcodes
+var shortcodeRegex = regexp.MustCompile(`:([a-z0-9_+-]+):`)
+
+// Shortcodes expanded without any configuration
+var builtinEmoji = map[string]string{
+	"+1":           "👍",
+	"-1":           "👎",
+	"bug":          "🐛",
+	"bulb":         "💡",
+	"check":        "✅",
+	"coffee":       "☕",
+	"construction": "🚧",
+	"eyes":         "👀",
+	"fire":         "🔥",
+	"heart":        "❤️",
+	"hourglass":    "⌛",
+	"joy":          "😂",
+	"memo":         "📝",
+	"pray":         "🙏",
+	"question":     "❓",
+	"rocket":       "🚀",
+	"smile":        "😄",
+	"sparkles":     "✨",
+	"tada":         "🎉",
+	"thinking":     "🤔",
+	"warning":      "⚠️",
+	"wave":         "👋",
+	"x":            "❌",
+	"zap":          "⚡",
+}
+
+// Parse an emoji_map setting of comma-separated name=emoji pairs
+func parseEmojiMap(v string) (map[string]string, error) {
+	m := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, emoji, ok := strings.Cut(pair, "=")
+		name, emoji = strings.Trim(strings.TrimSpace(name), ":"), strings.TrimSpace(emoji)
+		if !ok || name == "" || emoji == "" {
+			return nil, fmt.Errorf("expected name=emoji pairs, got %q", pair)
+		}
+		m[name] = emoji
+	}
+	return m, nil
+}
+
+// Replace known :shortcodes: with emoji. Custom entries from emoji_map take
+// precedence over the built-in ones; unknown shortcodes are left as typed.
+func expandShortcodes(text string) string {
+	custom, _ := parseEmojiMap(cfg.get("emoji_map"))
+	return shortcodeRegex.ReplaceAllStringFunc(text, func(code string) string {
+		name := strings.Trim(code, ":")
+		if e, ok := custom[name]; ok {
+			return e
+		}
+		if e, ok := builtinEmoji[name]; ok {
+			return e
+		}
+		return code
+	})
+}
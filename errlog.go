@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The command being run, recorded with logged errors
+var currentCommand string
+
+// Set while the REPL runs, so a failing command ends only its own line
+var inREPL bool
+
+// replAbort is raised instead of exiting the process while in the REPL
+type replAbort struct{}
+
+// Exit with the given status, or abandon the current REPL line
+func exit(code int) {
+	if inREPL {
+		panic(replAbort{})
+	}
+	os.Exit(code)
+}
+
+// Print an error the usual way, append it to the log file if one is
+// configured, and exit
+func fail(doing string, err error) {
+	if doing == "" {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	} else {
+		fmt.Fprintf(os.Stderr, "Error %s: %v\n", doing, err)
+	}
+	logError(doing, err)
+	exit(1)
+}
+
+// Append a structured line describing an error to the log file. Logging
+// problems are ignored so they never hide the original error.
+func logError(doing string, err error) {
+	// The config may not be loaded yet when argument parsing fails
+	path := os.Getenv("PROTHOUGHT_LOG")
+	if cfg != nil {
+		path = cfg.get("log_file")
+	}
+	if path == "" {
+		return
+	}
+
+	f, openErr := os.OpenFile(expandHome(path, homeDir), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if openErr != nil {
+		return
+	}
+	defer f.Close()
+
+	fields := []string{
+		"time=" + time.Now().Format(time.RFC3339),
+		"command=" + strconv.Quote(currentCommand),
+		"args=" + strconv.Quote(strings.Join(os.Args[1:], " ")),
+	}
+	if doing != "" {
+		fields = append(fields, "doing="+strconv.Quote(doing))
+	}
+	fields = append(fields, "error="+strconv.Quote(err.Error()))
+	log.New(f, "", 0).Println(strings.Join(fields, " "))
+}
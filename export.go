@@ -0,0 +1,697 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stringList is a flag that may be given several times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// exportOptions holds the flags accepted by export
+type exportOptions struct {
+	redact stringList
+	format string
+	fields string
+	json   bool
+	meta   bool
+	group  bool
+}
+
+// exportMeta describes an export for archival, written by --with-meta
+type exportMeta struct {
+	ExportedAt string   `json:"exported_at"`
+	Version    string   `json:"version"`
+	Period     string   `json:"period"`
+	From       string   `json:"from,omitempty"`
+	To         string   `json:"to,omitempty"`
+	Marker     string   `json:"marker,omitempty"`
+	Redacted   []string `json:"redacted,omitempty"`
+	Count      int      `json:"count"`
+}
+
+// List the metadata as key and value pairs for the text formats
+func (m exportMeta) pairs() [][2]string {
+	pairs := [][2]string{
+		{"exported_at", m.ExportedAt},
+		{"version", m.Version},
+		{"period", m.Period},
+	}
+	if m.From != "" {
+		pairs = append(pairs, [2]string{"from", m.From}, [2]string{"to", m.To})
+	}
+	if m.Marker != "" {
+		pairs = append(pairs, [2]string{"marker", "#" + m.Marker})
+	}
+	if len(m.Redacted) > 0 {
+		pairs = append(pairs, [2]string{"redacted", joinMarkers(m.Redacted)})
+	}
+	return append(pairs, [2]string{"count", strconv.Itoa(m.Count)})
+}
+
+// exportRecord is a thought prepared for export
+type exportRecord struct {
+	Thought
+	Markers []string
+	Struck  bool // kept separately so redaction doesn't hide it
+	Mood    *int // nil when no mood was given
+}
+
+// Fields emitted by structured export formats by default, in order
+var exportFields = []string{"id", "timestamp", "text", "markers"}
+
+// Fields that can be chosen with --fields
+var knownExportFields = []string{"id", "timestamp", "text", "markers", "struck", "mood"}
+
+// Get the value of a field for structured encoders
+func (r exportRecord) field(name string) interface{} {
+	switch name {
+	case "id":
+		return r.ID
+	case "timestamp":
+		return r.Timestamp
+	case "text":
+		return r.Text
+	case "markers":
+		return r.Markers
+	case "struck":
+		return r.Struck
+	case "mood":
+		return r.Mood
+	}
+	return nil
+}
+
+// Render a field as a single text cell
+func (r exportRecord) cell(name string) string {
+	if name == "markers" {
+		return strings.Join(r.Markers, " ")
+	}
+	if name == "mood" {
+		if r.Mood == nil {
+			return ""
+		}
+		return strconv.Itoa(*r.Mood)
+	}
+	return fmt.Sprint(r.field(name))
+}
+
+// orderedObject marshals to a JSON object keeping its keys in order
+type orderedObject struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Build the JSON object for a record with the selected fields
+func (r exportRecord) object(fields []string) orderedObject {
+	obj := orderedObject{keys: fields}
+	for _, f := range fields {
+		obj.values = append(obj.values, r.field(f))
+	}
+	return obj
+}
+
+// Parse a comma-separated --fields list against the known fields
+func parseFields(list string, defaults []string) ([]string, error) {
+	if list == "" {
+		return defaults, nil
+	}
+
+	var fields []string
+	for _, f := range strings.Split(list, ",") {
+		f = strings.ToLower(strings.TrimSpace(f))
+		known := false
+		for _, k := range knownExportFields {
+			if f == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown field %q (known fields: %s)", f, strings.Join(knownExportFields, ", "))
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// Normalize a marker given on the command line (#Tag or Tag) to its stored form
+func normalizeMarkerArg(marker string) string {
+	return normalizeMarker(strings.TrimPrefix(marker, "#"))
+}
+
+// Export thoughts for a period, redacting sensitive markers
+func exportThoughts(db *sql.DB, w io.Writer, cmd string, args []string) error {
+	var opts exportOptions
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.Var(&opts.redact, "redact", "replace the text of thoughts with this marker (repeatable)")
+	fs.StringVar(&opts.format, "format", "text", "output format: text, csv, tsv, json, ndjson or org")
+	fs.StringVar(&opts.fields, "fields", "", "comma-separated fields for csv, tsv and json")
+	fs.BoolVar(&opts.json, "json", false, "shorthand for --format json")
+	fs.BoolVar(&opts.meta, "with-meta", false, "start with export time, version, period, filters and count")
+	fs.BoolVar(&opts.group, "group-by-marker", false, "group thoughts under a heading per marker")
+	sinceLast := fs.Bool("since-last-export", false, "only thoughts logged since the previous export")
+	noUpdate := fs.Bool("no-update", false, "with --since-last-export, don't record this export")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if opts.json {
+		opts.format = "json"
+	}
+
+	defaults := exportFields
+	switch opts.format {
+	case "ndjson":
+		// Log pipelines get everything, including the struck flag
+		defaults = knownExportFields
+	case "json":
+		defaults = append(exportFields[:len(exportFields):len(exportFields)], "mood")
+	}
+	fields, err := parseFields(opts.fields, defaults)
+	if err != nil {
+		return err
+	}
+	if opts.fields != "" && (opts.format == "text" || opts.format == "org") {
+		return fmt.Errorf("--fields only applies to csv, tsv, json and ndjson")
+	}
+
+	if opts.meta && opts.format != "json" && opts.format != "text" && opts.format != "org" {
+		return fmt.Errorf("--with-meta only applies to json, text and org")
+	}
+	if opts.group && opts.format != "json" && opts.format != "text" && opts.format != "org" {
+		return fmt.Errorf("--group-by-marker only applies to json, text and org")
+	}
+
+	if *noUpdate && !*sinceLast {
+		return fmt.Errorf("--no-update only applies to --since-last-export")
+	}
+
+	periodArgs, marker := parseArgsWithMarker(rest)
+	var q thoughtQuery
+	var exportKey string
+	if *sinceLast {
+		if len(periodArgs) > 0 {
+			return fmt.Errorf("--since-last-export cannot be combined with a period")
+		}
+		if exportKey, err = lastExportKey(); err != nil {
+			return err
+		}
+		if q, err = sinceLastExportQuery(db, exportKey); err != nil {
+			return err
+		}
+	} else if q, err = periodQuery(periodArgs); err != nil {
+		return err
+	}
+	q.marker = marker
+
+	redacted := make(map[string]bool)
+	for _, m := range opts.redact {
+		redacted[normalizeMarkerArg(m)] = true
+	}
+
+	if opts.format == "ndjson" {
+		count, err := streamNDJSON(db, w, q, fields, redacted)
+		if err != nil {
+			return err
+		}
+		if !*sinceLast {
+			return nil
+		}
+		return recordSinceLastExport(q, exportKey, count, *noUpdate)
+	}
+
+	thoughts, err := queryThoughts(db, q)
+	if err != nil {
+		return err
+	}
+	markers, err := markersForThoughts(db, thoughts)
+	if err != nil {
+		return err
+	}
+	moods, err := moodsForThoughts(db, thoughts)
+	if err != nil {
+		return err
+	}
+
+	records := make([]exportRecord, len(thoughts))
+	for i, t := range thoughts {
+		records[i] = newExportRecord(t, markers[t.ID], redacted)
+		if mood, ok := moods[t.ID]; ok {
+			records[i].Mood = &mood
+		}
+	}
+
+	var meta *exportMeta
+	if opts.meta {
+		meta = &exportMeta{
+			ExportedAt: time.Now().Format(time.RFC3339),
+			Version:    version,
+			Period:     strings.Join(periodArgs, " "),
+			From:       q.start,
+			To:         q.end,
+			Marker:     marker,
+			Count:      len(records),
+		}
+		switch {
+		case *sinceLast:
+			meta.Period = "since last export"
+		case len(periodArgs) == 0:
+			meta.Period = cfg.get("default_period")
+		}
+		for _, m := range opts.redact {
+			meta.Redacted = append(meta.Redacted, normalizeMarkerArg(m))
+		}
+	}
+
+	write := writeRecords
+	if opts.group {
+		write = writeGroupedRecords
+	}
+	if err := write(w, records, fields, opts.format, meta); err != nil {
+		return err
+	}
+	if !*sinceLast {
+		return nil
+	}
+	return recordSinceLastExport(q, exportKey, len(records), *noUpdate)
+}
+
+// Build the export record of a thought, hiding its text when it carries a
+// redacted marker
+func newExportRecord(t Thought, tags []string, redacted map[string]bool) exportRecord {
+	if tags == nil {
+		tags = []string{}
+	}
+	struck := isStruck(t.Text)
+	for _, m := range tags {
+		if redacted[m] {
+			t.Text = "[redacted]"
+			break
+		}
+	}
+	return exportRecord{Thought: t, Markers: tags, Struck: struck}
+}
+
+// Report a --since-last-export run and, unless told not to, record how far
+// it got
+func recordSinceLastExport(q thoughtQuery, key string, count int, noUpdate bool) error {
+	// Report on stderr so the exported data on stdout stays clean
+	if count == 0 {
+		fmt.Fprintf(os.Stderr, "No thoughts since the last export (up to id %d)\n", q.after)
+	} else {
+		fmt.Fprintf(os.Stderr, "Exported %d thought(s) with ids %d to %d\n", count, q.after+1, q.upTo)
+	}
+	if noUpdate || q.upTo == q.after {
+		return nil
+	}
+	return writeConfigValue(configPath, key, strconv.FormatInt(q.upTo, 10))
+}
+
+// Name the config entry holding the last exported id of this database, so
+// each database keeps its own high-water mark
+func lastExportKey() (string, error) {
+	path := dbPath
+	if path != ":memory:" {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return "", fmt.Errorf("resolve database path: %w", err)
+		}
+		path = abs
+	}
+	return "last_export_id@" + path, nil
+}
+
+// Select thoughts added after the previous export, by id so that thoughts
+// logged within the same second, or backdated, are never skipped. Ids are
+// capped at the current maximum so the recorded mark covers exactly what
+// was selected.
+func sinceLastExportQuery(db *sql.DB, key string) (thoughtQuery, error) {
+	var q thoughtQuery
+	if err := db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM thoughts").Scan(&q.upTo); err != nil {
+		return q, fmt.Errorf("query last id: %w", err)
+	}
+
+	file, err := readConfigFile(configPath)
+	if err != nil {
+		return q, err
+	}
+	if v, ok := file[key]; ok {
+		if q.after, err = strconv.ParseInt(v, 10, 64); err != nil || q.after < 0 {
+			return q, fmt.Errorf("%s in %s must be a thought id, got %q", key, configPath, v)
+		}
+	} else if last := cfg.get("last_export"); last != "" {
+		// Carry on from a timestamp recorded by older versions
+		if err := db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM thoughts WHERE substr(timestamp, 1, 19) <= ?", last).Scan(&q.after); err != nil {
+			return q, fmt.Errorf("query last exported id: %w", err)
+		}
+	}
+	// The newest thoughts may have been purged since
+	if q.upTo < q.after {
+		q.upTo = q.after
+	}
+	return q, nil
+}
+
+// Write records in the given format, preceded by the metadata if given
+func writeRecords(w io.Writer, records []exportRecord, fields []string, format string, meta *exportMeta) error {
+	switch format {
+	case "text":
+		writeTextMeta(w, meta)
+		for _, r := range records {
+			fmt.Fprintf(w, "[%s] %s\n", r.Timestamp, r.Text)
+		}
+		return nil
+	case "csv", "tsv":
+		return writeDelimited(w, records, fields, format == "tsv")
+	case "json":
+		return writeJSON(w, records, fields, meta)
+	case "org":
+		writeOrgMeta(w, meta)
+		return writeOrg(w, records)
+	}
+	return fmt.Errorf("unsupported export format: %s", format)
+}
+
+// Write the metadata as front matter, as in Markdown
+func writeTextMeta(w io.Writer, meta *exportMeta) {
+	if meta == nil {
+		return
+	}
+	fmt.Fprintln(w, "---")
+	for _, p := range meta.pairs() {
+		fmt.Fprintf(w, "%s: %s\n", p[0], p[1])
+	}
+	fmt.Fprintln(w, "---")
+}
+
+// Write the metadata as org-mode keywords
+func writeOrgMeta(w io.Writer, meta *exportMeta) {
+	if meta == nil {
+		return
+	}
+	fmt.Fprintln(w, "#+TITLE: prothought export")
+	for _, p := range meta.pairs() {
+		fmt.Fprintf(w, "#+%s: %s\n", strings.ToUpper(p[0]), p[1])
+	}
+	fmt.Fprintln(w)
+}
+
+// markerGroup holds the records filed under one marker heading
+type markerGroup struct {
+	Heading string
+	Records []exportRecord
+}
+
+// Heading of the group for thoughts without markers
+const untaggedHeading = "untagged"
+
+// File records under each of their markers, in marker order, so a thought
+// with several markers appears in several groups. Thoughts without markers
+// are collected last under untagged.
+func groupByMarker(records []exportRecord) []markerGroup {
+	byMarker := make(map[string][]exportRecord)
+	var untagged []exportRecord
+	for _, r := range records {
+		if len(r.Markers) == 0 {
+			untagged = append(untagged, r)
+			continue
+		}
+		for _, m := range r.Markers {
+			byMarker[m] = append(byMarker[m], r)
+		}
+	}
+
+	markers := make([]string, 0, len(byMarker))
+	for m := range byMarker {
+		markers = append(markers, m)
+	}
+	sort.Strings(markers)
+
+	groups := make([]markerGroup, 0, len(markers)+1)
+	for _, m := range markers {
+		groups = append(groups, markerGroup{Heading: "#" + m, Records: byMarker[m]})
+	}
+	if len(untagged) > 0 {
+		groups = append(groups, markerGroup{Heading: untaggedHeading, Records: untagged})
+	}
+	return groups
+}
+
+// Write records grouped under a heading per marker in the given format,
+// preceded by the metadata if given
+func writeGroupedRecords(w io.Writer, records []exportRecord, fields []string, format string, meta *exportMeta) error {
+	groups := groupByMarker(records)
+	switch format {
+	case "text":
+		writeTextMeta(w, meta)
+		for i, g := range groups {
+			if i > 0 {
+				fmt.Fprintln(w)
+			}
+			fmt.Fprintf(w, "## %s\n", g.Heading)
+			for _, r := range g.Records {
+				fmt.Fprintf(w, "[%s] %s\n", r.Timestamp, r.Text)
+			}
+		}
+		return nil
+	case "json":
+		return writeGroupedJSON(w, groups, fields, meta)
+	case "org":
+		writeOrgMeta(w, meta)
+		for _, g := range groups {
+			if _, err := fmt.Fprintf(w, "* %s\n", g.Heading); err != nil {
+				return fmt.Errorf("write org: %w", err)
+			}
+			for _, r := range g.Records {
+				if err := writeOrgThought(w, r, r.Timestamp[:10]+" "+r.Timestamp[11:16]); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("--group-by-marker does not support format: %s", format)
+}
+
+// Write groups as an indented JSON object from heading to an array of
+// thoughts, or with metadata as an object holding both
+func writeGroupedJSON(w io.Writer, groups []markerGroup, fields []string, meta *exportMeta) error {
+	var obj orderedObject
+	for _, g := range groups {
+		objects := make([]orderedObject, len(g.Records))
+		for i, r := range g.Records {
+			objects[i] = r.object(fields)
+		}
+		obj.keys = append(obj.keys, g.Heading)
+		obj.values = append(obj.values, objects)
+	}
+
+	var v interface{} = obj
+	if meta != nil {
+		v = struct {
+			Meta   *exportMeta   `json:"meta"`
+			Groups orderedObject `json:"groups"`
+		}{meta, obj}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+	return nil
+}
+
+// Write records as CSV, or TSV when tabs is set, with a header row
+func writeDelimited(w io.Writer, records []exportRecord, fields []string, tabs bool) error {
+	cw := csv.NewWriter(w)
+	if tabs {
+		cw.Comma = '\t'
+	}
+
+	if err := cw.Write(fields); err != nil {
+		return fmt.Errorf("write header: %w", err)
+	}
+	row := make([]string, len(fields))
+	for _, r := range records {
+		for i, f := range fields {
+			row[i] = r.cell(f)
+		}
+		if err := cw.Write(row); err != nil {
+			return fmt.Errorf("write row: %w", err)
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// Write records as an indented JSON array, or with metadata as an object
+// holding the metadata and the array
+func writeJSON(w io.Writer, records []exportRecord, fields []string, meta *exportMeta) error {
+	objects := make([]orderedObject, len(records))
+	for i, r := range records {
+		objects[i] = r.object(fields)
+	}
+
+	var v interface{} = objects
+	if meta != nil {
+		v = struct {
+			Meta     *exportMeta     `json:"meta"`
+			Thoughts []orderedObject `json:"thoughts"`
+		}{meta, objects}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		return fmt.Errorf("encode json: %w", err)
+	}
+	return nil
+}
+
+// Stream thoughts as newline-delimited JSON, one compact object per line,
+// straight from the cursor. Markers and mood come from the same query, so
+// nothing is held in memory beyond the current row. Returns how many
+// thoughts were written.
+func streamNDJSON(db *sql.DB, w io.Writer, q thoughtQuery, fields []string, redacted map[string]bool) (int, error) {
+	query, args := q.build()
+	rows, err := db.Query(`
+		SELECT e.id, e.timestamp, e.text, t.mood,
+			(SELECT json_group_array(tag) FROM (
+				SELECT CASE WHEN value = '' THEN marker ELSE marker || '=' || value END AS tag
+				FROM markers
+				WHERE thought_id = e.id
+				ORDER BY id ASC
+			)) AS tags
+		FROM (`+query+`) e
+		JOIN thoughts t ON t.id = e.id
+		ORDER BY e.timestamp ASC, e.id ASC`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("query thoughts: %w", err)
+	}
+	defer rows.Close()
+
+	enc := json.NewEncoder(w)
+	count := 0
+	for rows.Next() {
+		var t Thought
+		var mood sql.NullInt64
+		var tagsJSON string
+		if err := rows.Scan(&t.ID, &t.Timestamp, &t.Text, &mood, &tagsJSON); err != nil {
+			return count, fmt.Errorf("scan thought: %w", err)
+		}
+		var tags []string
+		if err := json.Unmarshal([]byte(tagsJSON), &tags); err != nil {
+			return count, fmt.Errorf("decode markers of thought %d: %w", t.ID, err)
+		}
+		r := newExportRecord(t, tags, redacted)
+		if mood.Valid {
+			m := int(mood.Int64)
+			r.Mood = &m
+		}
+		if err := enc.Encode(r.object(fields)); err != nil {
+			return count, fmt.Errorf("encode json: %w", err)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("query thoughts: %w", err)
+	}
+	return count, nil
+}
+
+// orgTagInvalidRegex matches characters org does not allow in tags
+var orgTagInvalidRegex = regexp.MustCompile(`[^\p{L}\p{N}_@#%]`)
+
+// Format markers as an org tag list such as :work:priority_high:
+func orgTags(markers []string) string {
+	tags := make([]string, len(markers))
+	for i, m := range markers {
+		tags[i] = orgTagInvalidRegex.ReplaceAllString(m, "_")
+	}
+	return ":" + strings.Join(tags, ":") + ":"
+}
+
+// Write records as an org-mode outline: a heading per day and a
+// subheading per thought, with markers as org tags
+func writeOrg(w io.Writer, records []exportRecord) error {
+	day := ""
+	for _, r := range records {
+		if r.Timestamp[:10] != day {
+			day = r.Timestamp[:10]
+			if _, err := fmt.Fprintf(w, "* %s\n", day); err != nil {
+				return fmt.Errorf("write org: %w", err)
+			}
+		}
+
+		if err := writeOrgThought(w, r, r.Timestamp[11:16]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Write a thought as an org subheading starting with when
+func writeOrgThought(w io.Writer, r exportRecord, when string) error {
+	// Only the first line goes in the heading; the rest is indented
+	// body text so a leading * can't start a new heading
+	lines := strings.Split(strings.TrimSpace(r.Text), "\n")
+	heading := fmt.Sprintf("** %s %s", when, strings.TrimSpace(lines[0]))
+	if len(r.Markers) > 0 {
+		heading += " " + orgTags(r.Markers)
+	}
+	if _, err := fmt.Fprintln(w, heading); err != nil {
+		return fmt.Errorf("write org: %w", err)
+	}
+	for _, line := range lines[1:] {
+		if _, err := fmt.Fprintf(w, "   %s\n", strings.TrimRight(line, " \t\r")); err != nil {
+			return fmt.Errorf("write org: %w", err)
+		}
+	}
+	return nil
+}
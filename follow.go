@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Print the most recent thoughts, then keep printing new ones as they are
+// logged until ctx is cancelled
+func followThoughts(ctx context.Context, db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	n := fs.Int("n", 10, "number of recent thoughts to show first")
+	interval := fs.Duration("interval", time.Second, "how often to check for new thoughts")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *n < 0 {
+		return fmt.Errorf("-n cannot be negative")
+	}
+	if *interval <= 0 {
+		return fmt.Errorf("--interval must be positive")
+	}
+
+	var marker string
+	for _, arg := range rest {
+		if !strings.HasPrefix(arg, "#") {
+			return fmt.Errorf("usage: prothought follow [#marker] [-n N] [--interval 1s]")
+		}
+		marker = strings.TrimPrefix(arg, "#")
+	}
+
+	// Start after the newest thought so only later ones are picked up
+	var last int64
+	if err := db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM thoughts").Scan(&last); err != nil {
+		return fmt.Errorf("query last thought: %w", err)
+	}
+	if *n > 0 {
+		recent, err := queryThoughts(db, thoughtQuery{marker: marker, limit: *n})
+		if err != nil {
+			return err
+		}
+		for _, t := range recent {
+			fmt.Println(formatThought(t))
+		}
+	}
+
+	return runLoop(ctx, *interval, func() error {
+		thoughts, err := queryThoughts(db, thoughtQuery{marker: marker, after: last})
+		if err != nil {
+			return err
+		}
+		for _, t := range thoughts {
+			fmt.Println(formatThought(t))
+			if t.ID > last {
+				last = t.ID
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+// gitValueReplacer matches characters not allowed in a marker value, such
+// as the slash in feature/login
+var gitValueReplacer = regexp.MustCompile(`[^\p{L}\p{M}\p{N}_.-]+`)
+
+// Run a git rev-parse query in the current directory
+func gitRevParse(args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"rev-parse"}, args...)...).Output()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Describe the git checkout in the current directory as #branch= and
+// #commit= markers. Outside a repository, without commits or without git
+// installed there is nothing to describe and ok is false.
+func gitMarkers() (tags []string, ok bool) {
+	commit, err := gitRevParse("--short", "HEAD")
+	if err != nil || commit == "" {
+		return nil, false
+	}
+	// A detached HEAD has no branch
+	if branch, err := gitRevParse("--abbrev-ref", "HEAD"); err == nil && branch != "HEAD" {
+		value := strings.Trim(gitValueReplacer.ReplaceAllString(branch, "-"), ".-")
+		if value != "" {
+			tags = append(tags, "#branch="+value)
+		}
+	}
+	tags = append(tags, "#commit="+commit)
+	return extractHashtags(strings.Join(tags, " ")), true
+}
+
+// Add markers to a thought's markers, skipping any it already carries
+func appendMissingMarkers(hashtags []string, extra []string) []string {
+	has := make(map[string]bool)
+	for _, tag := range hashtags {
+		has[tag] = true
+	}
+	for _, tag := range extra {
+		if !has[tag] {
+			hashtags = append(hashtags, tag)
+		}
+	}
+	return hashtags
+}
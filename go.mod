@@ -2,4 +2,10 @@ module github.com/povilaspetkevicius/prothought
 
 go 1.21
 
-require github.com/mattn/go-sqlite3 v1.14.22
+require (
+	github.com/mattn/go-sqlite3 v1.14.22
+	golang.org/x/term v0.20.0
+	golang.org/x/text v0.15.0
+)
+
+require golang.org/x/sys v0.20.0 // indirect
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Keep a thought's current text in its history before it is replaced
+func recordHistoryTx(tx *sql.Tx, id int64) error {
+	if _, err := tx.Exec(`
+		INSERT INTO thought_history (thought_id, text, replaced_at)
+		SELECT id, text, ? FROM thoughts WHERE id = ?`,
+		time.Now().Format(timestampFormat), id); err != nil {
+		return fmt.Errorf("record history: %w", err)
+	}
+	return nil
+}
+
+// Print the earlier versions of a thought, most recent first
+func showHistory(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: prothought history <id>")
+	}
+	id, err := parseThoughtID(args[0])
+	if err != nil {
+		return err
+	}
+
+	var current string
+	err = db.QueryRow("SELECT text FROM thoughts WHERE id = ?", id).Scan(&current)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no thought with id %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("query thought: %w", err)
+	}
+
+	rows, err := db.Query(`
+		SELECT text, replaced_at
+		FROM thought_history
+		WHERE thought_id = ?
+		ORDER BY replaced_at DESC, id DESC`, id)
+	if err != nil {
+		return fmt.Errorf("query history: %w", err)
+	}
+	defer rows.Close()
+
+	type version struct{ text, replacedAt string }
+	var versions []version
+	for rows.Next() {
+		var v version
+		if err := rows.Scan(&v.text, &v.replacedAt); err != nil {
+			return fmt.Errorf("scan history: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query history: %w", err)
+	}
+
+	if len(versions) == 0 {
+		fmt.Printf("Thought %d has never been edited.\n", id)
+		return nil
+	}
+
+	fmt.Printf("Thought %d, %d earlier version(s)\n", id, len(versions))
+	fmt.Printf("\nCurrent:\n  %s\n", highlightHashtags(strings.TrimSpace(current)))
+	for i, v := range versions {
+		fmt.Printf("\nVersion %d, replaced %s:\n  %s\n", len(versions)-i, displayTime(v.replacedAt), highlightHashtags(strings.TrimSpace(v.text)))
+	}
+	return nil
+}
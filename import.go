@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// importRecord is one thought as produced by export --json
+type importRecord struct {
+	ID        *int64    `json:"id"`
+	Timestamp string    `json:"timestamp"`
+	Text      string    `json:"text"`
+	Markers   *[]string `json:"markers"`
+	Struck    *bool     `json:"struck"`
+	Mood      *int      `json:"mood"`
+}
+
+// Import thoughts from a file (or - for stdin)
+func importThoughts(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "prothought-json", "input format")
+	lenient := fs.Bool("lenient", false, "ignore unknown fields")
+	deferMarkers := fs.Bool("defer-markers", false, "skip marker inserts until reindex-markers runs")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: prothought import [--format=prothought-json] [--lenient] [--defer-markers] <file|->")
+	}
+	if *format != "prothought-json" {
+		return fmt.Errorf("unsupported import format: %s", *format)
+	}
+
+	var r io.Reader = os.Stdin
+	if rest[0] != "-" {
+		f, err := os.Open(rest[0])
+		if err != nil {
+			return fmt.Errorf("open import file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	records, err := decodeProthoughtJSON(r, *lenient)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	markerCount := 0
+	for _, rec := range records {
+		// The struck flag survives redaction, so restore it from there
+		if rec.Struck != nil && *rec.Struck && !isStruck(rec.Text) {
+			rec.Text = strikeText(rec.Text)
+		}
+		result, err := tx.Exec("INSERT INTO thoughts (timestamp, text, mood) VALUES (?, ?, ?)", rec.Timestamp, rec.Text, rec.Mood)
+		if err != nil {
+			return fmt.Errorf("insert thought: %w", err)
+		}
+		// Ids are reassigned by the database; the exported id is not kept
+		thoughtID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("get last insert id: %w", err)
+		}
+
+		markers := extractHashtags(rec.Text)
+		if rec.Markers != nil {
+			markers = *rec.Markers
+		}
+		if *deferMarkers {
+			markers = nil
+		}
+		tags := make([]string, len(markers))
+		for i, m := range markers {
+			// Validated when decoding
+			tags[i], _ = parseLoggableMarker(m)
+		}
+		if err := insertMarkers(tx, thoughtID, tags); err != nil {
+			return err
+		}
+		markerCount += len(tags)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Printf("Imported %d thought(s) with %d marker(s).\n", len(records), markerCount)
+	return nil
+}
+
+// Decode and validate the JSON array written by export --json
+func decodeProthoughtJSON(r io.Reader, lenient bool) ([]importRecord, error) {
+	br := bufio.NewReader(r)
+	var records []importRecord
+	if first, err := firstNonSpace(br); err == nil && first == '{' {
+		// Exports made --with-meta wrap the array in an object
+		var wrapped map[string]json.RawMessage
+		if err := json.NewDecoder(br).Decode(&wrapped); err != nil {
+			return nil, fmt.Errorf("decode prothought-json: %w", err)
+		}
+		thoughts, ok := wrapped["thoughts"]
+		if !ok {
+			if _, grouped := wrapped["groups"]; grouped || isGroupedExport(wrapped) {
+				return nil, fmt.Errorf("this is an export made with --group-by-marker, which lists thoughts once per marker; import an export made without it")
+			}
+			return nil, fmt.Errorf("decode prothought-json: expected an array of thoughts or an object with meta and thoughts")
+		}
+		for key := range wrapped {
+			if key != "meta" && key != "thoughts" && !lenient {
+				return nil, fmt.Errorf("decode prothought-json: unknown field %q", key)
+			}
+		}
+		br = bufio.NewReader(bytes.NewReader(thoughts))
+	}
+
+	dec := json.NewDecoder(br)
+	if !lenient {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(&records); err != nil {
+		return nil, fmt.Errorf("decode prothought-json: %w", err)
+	}
+
+	for i, rec := range records {
+		if rec.Timestamp == "" {
+			return nil, fmt.Errorf("record %d: missing timestamp", i+1)
+		}
+		if _, err := time.Parse(timestampFormat, rec.Timestamp); err != nil {
+			return nil, fmt.Errorf("record %d: invalid timestamp %q", i+1, rec.Timestamp)
+		}
+		if rec.Text == "" {
+			return nil, fmt.Errorf("record %d: missing text", i+1)
+		}
+		if rec.Mood != nil && (*rec.Mood < 1 || *rec.Mood > maxMood) {
+			return nil, fmt.Errorf("record %d: mood %d is outside 1 to %d", i+1, *rec.Mood, maxMood)
+		}
+		if rec.Markers != nil {
+			for _, m := range *rec.Markers {
+				if _, ok := parseLoggableMarker(m); !ok {
+					return nil, fmt.Errorf("record %d: invalid marker %q", i+1, m)
+				}
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// Report whether an object is keyed by the headings of export
+// --group-by-marker
+func isGroupedExport(obj map[string]json.RawMessage) bool {
+	if len(obj) == 0 {
+		return false
+	}
+	for key := range obj {
+		if key != untaggedHeading && !strings.HasPrefix(key, "#") {
+			return false
+		}
+	}
+	return true
+}
+
+// Skip leading whitespace and return the next byte without consuming it
+func firstNonSpace(br *bufio.Reader) (byte, error) {
+	for {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b != ' ' && b != '\t' && b != '\n' && b != '\r' {
+			return b, br.UnreadByte()
+		}
+	}
+}
+
+// Log every non-empty line of a file (or - for stdin) as its own thought,
+// all in one transaction
+func logFile(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	deferMarkers := fs.Bool("defer-markers", false, "skip marker inserts until reindex-markers runs")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: prothought log-file [--defer-markers] <file|->")
+	}
+
+	var r io.Reader = os.Stdin
+	if rest[0] != "-" {
+		f, err := os.Open(rest[0])
+		if err != nil {
+			return fmt.Errorf("open file: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	count, markerCount := 0, 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if cfg.enabled("emoji") {
+			text = expandShortcodes(text)
+		}
+
+		result, err := tx.Exec("INSERT INTO thoughts (timestamp, text) VALUES (?, ?)", nowTimestamp(), text)
+		if err != nil {
+			return fmt.Errorf("insert thought: %w", err)
+		}
+		thoughtID, err := result.LastInsertId()
+		if err != nil {
+			return fmt.Errorf("get last insert id: %w", err)
+		}
+
+		var hashtags []string
+		if !*deferMarkers {
+			hashtags = extractHashtags(text)
+		}
+		hashtags = withDefaultMarker(hashtags)
+		if err := insertMarkers(tx, thoughtID, hashtags); err != nil {
+			return err
+		}
+		if err := insertAttachments(tx, thoughtID, extractAttachments(text)); err != nil {
+			return err
+		}
+		count++
+		markerCount += len(hashtags)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("read %s: %w", rest[0], err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Printf("Logged %d thought(s) with %d marker(s).\n", count, markerCount)
+	return nil
+}
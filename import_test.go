@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Export every thought of a database as JSON with all fields
+func exportAll(t *testing.T, db *sql.DB) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := exportThoughts(db, &buf, "export", []string{"last:100", "--format", "json", "--fields", "id,timestamp,text,markers,struck,mood"}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	return buf.String()
+}
+
+func TestImportRoundTrip(t *testing.T) {
+	src := newTestDB(t)
+	mustLog(t, src, "Fixed the login redirect #work #priority=high")
+	var err error
+	captureStdout(t, func() { err = logThought(src, "Good day overall", addOptions{mood: 4}) })
+	if err != nil {
+		t.Fatalf("log with mood: %v", err)
+	}
+	mustLog(t, src, "Wrong idea #ideas")
+	captureStdout(t, func() {
+		if err := tagPeriod(src, "tag-period", []string{"today", "#reviewed", "--yes"}); err != nil {
+			t.Fatalf("tag-period: %v", err)
+		}
+		runCommand(src, "nvm", nil)
+	})
+
+	exported := exportAll(t, src)
+	for _, want := range []string{`"reviewed"`, `"priority=high"`, `"struck": true`, `"mood": 4`} {
+		if !strings.Contains(exported, want) {
+			t.Fatalf("export is missing %s:\n%s", want, exported)
+		}
+	}
+	path := filepath.Join(t.TempDir(), "backup.json")
+	if err := os.WriteFile(path, []byte(exported), 0o644); err != nil {
+		t.Fatalf("write export: %v", err)
+	}
+
+	dst := newTestDB(t)
+	captureStdout(t, func() { err = importThoughts(dst, "import", []string{"--format=prothought-json", path}) })
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if imported := exportAll(t, dst); imported != exported {
+		t.Errorf("round trip changed the export:\n%s\nwant:\n%s", imported, exported)
+	}
+}
+
+func TestImportRefusesGroupedExport(t *testing.T) {
+	db := newTestDB(t)
+	mustLog(t, db, "Filed twice #work #ideas")
+
+	var grouped bytes.Buffer
+	if err := exportThoughts(db, &grouped, "export", []string{"today", "--format", "json", "--group-by-marker"}); err != nil {
+		t.Fatalf("export: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "grouped.json")
+	if err := os.WriteFile(path, grouped.Bytes(), 0o644); err != nil {
+		t.Fatalf("write export: %v", err)
+	}
+
+	err := importThoughts(db, "import", []string{path})
+	if err == nil || !strings.Contains(err.Error(), "--group-by-marker") {
+		t.Errorf("import of a grouped export = %v, want it refused", err)
+	}
+}
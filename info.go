@@ -0,0 +1,88 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Print database location, size and content overview
+func showInfo(db *sql.DB) error {
+	var sqliteVersion string
+	if err := db.QueryRow("SELECT sqlite_version()").Scan(&sqliteVersion); err != nil {
+		return fmt.Errorf("query sqlite version: %w", err)
+	}
+
+	var thoughtCount, trashCount, markerCount, distinctMarkers int
+	var first, last sql.NullString
+	if err := db.QueryRow(`
+		SELECT COUNT(*), MIN(timestamp), MAX(timestamp)
+		FROM thoughts
+		WHERE deleted_at IS NULL`).Scan(&thoughtCount, &first, &last); err != nil {
+		return fmt.Errorf("query thought counts: %w", err)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM thoughts WHERE deleted_at IS NOT NULL").Scan(&trashCount); err != nil {
+		return fmt.Errorf("query trash count: %w", err)
+	}
+	if err := db.QueryRow(`
+		SELECT COUNT(*), COUNT(DISTINCT marker)
+		FROM markers`).Scan(&markerCount, &distinctMarkers); err != nil {
+		return fmt.Errorf("query marker counts: %w", err)
+	}
+
+	size := "unknown"
+	if fi, err := os.Stat(dbPath); err == nil {
+		size = formatBytes(fi.Size())
+	}
+
+	dateRange := "none"
+	if first.Valid && last.Valid {
+		dateRange = fmt.Sprintf("%s .. %s", first.String, last.String)
+	}
+
+	fmt.Printf("Database:       %s\n", dbPath)
+	fmt.Printf("File size:      %s\n", size)
+	fmt.Printf("Thoughts:       %d\n", thoughtCount)
+	fmt.Printf("In trash:       %d\n", trashCount)
+	fmt.Printf("Markers:        %d (%d distinct)\n", markerCount, distinctMarkers)
+	fmt.Printf("Date range:     %s\n", dateRange)
+	fmt.Printf("SQLite version: %s\n", sqliteVersion)
+
+	return nil
+}
+
+// Format a byte count in human readable units
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Parse a byte count such as 512, 10KB or 2MB
+func parseSize(s string) (int64, error) {
+	n := strings.ToUpper(strings.TrimSpace(s))
+	mult := int64(1)
+	for _, u := range []struct {
+		suffix string
+		mult   int64
+	}{{"KB", 1 << 10}, {"MB", 1 << 20}, {"GB", 1 << 30}, {"B", 1}} {
+		if strings.HasSuffix(n, u.suffix) {
+			n, mult = strings.TrimSpace(strings.TrimSuffix(n, u.suffix)), u.mult
+			break
+		}
+	}
+	v, err := strconv.ParseInt(n, 10, 64)
+	if err != nil || v < 0 {
+		return 0, fmt.Errorf("invalid size: %s", s)
+	}
+	return v * mult, nil
+}
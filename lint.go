@@ -0,0 +1,168 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parse marker pairs given as "todo:done, draft:published"
+func parseMarkerPairs(v string) ([][2]string, error) {
+	var pairs [][2]string
+	for _, entry := range strings.Split(v, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		a, b, ok := strings.Cut(entry, ":")
+		a, b = strings.TrimPrefix(strings.TrimSpace(a), "#"), strings.TrimPrefix(strings.TrimSpace(b), "#")
+		if !ok || !markerNameRegex.MatchString(a) || !markerNameRegex.MatchString(b) {
+			return nil, fmt.Errorf("expected marker:marker pairs separated by commas, got %q", entry)
+		}
+		pairs = append(pairs, [2]string{a, b})
+	}
+	return pairs, nil
+}
+
+// Report likely tagging problems: rarely used markers, markers that are
+// a few edits apart and thoughts carrying markers that shouldn't go together
+func lintMarkers(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	minUses := fs.Int("min-uses", 2, "flag markers used on fewer thoughts than this")
+	maxDistance := fs.Int("max-distance", 1, "flag marker pairs at most this many edits apart")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument: %s", rest[0])
+	}
+	if *minUses < 0 || *maxDistance < 0 {
+		return fmt.Errorf("--min-uses and --max-distance cannot be negative")
+	}
+	conflicts, err := parseMarkerPairs(cfg.get("lint_conflicts"))
+	if err != nil {
+		return err
+	}
+
+	counts, err := countMarkersForQuery(db, thoughtQuery{})
+	if err != nil {
+		return err
+	}
+	uses := make(map[string]int)
+	for _, mc := range counts {
+		uses[mc.Marker] = mc.Count
+	}
+
+	// Near-duplicates, suggesting the less used marker be merged into the
+	// more used one. Very short markers are skipped as they are all close.
+	type nearPair struct{ from, to string }
+	var near []nearPair
+	merge := make(map[string]string)
+	for i, a := range counts {
+		for _, b := range counts[i+1:] {
+			shortest := min(utf8.RuneCountInString(a.Marker), utf8.RuneCountInString(b.Marker))
+			if shortest <= 3 || levenshtein(a.Marker, b.Marker) > *maxDistance {
+				continue
+			}
+			// counts is ordered most used first, so b is the rarer one
+			near = append(near, nearPair{from: b.Marker, to: a.Marker})
+			if _, ok := merge[b.Marker]; !ok {
+				merge[b.Marker] = a.Marker
+			}
+		}
+	}
+
+	var rare []markerCount
+	for _, mc := range counts {
+		if mc.Count < *minUses {
+			rare = append(rare, mc)
+		}
+	}
+
+	type conflict struct {
+		a, b string
+		ids  []int64
+	}
+	var found []conflict
+	conflicting := 0
+	for _, pair := range conflicts {
+		a, b := normalizeMarker(pair[0]), normalizeMarker(pair[1])
+		ids, err := thoughtsWithBothMarkers(db, a, b)
+		if err != nil {
+			return err
+		}
+		if len(ids) > 0 {
+			found = append(found, conflict{a: a, b: b, ids: ids})
+			conflicting += len(ids)
+		}
+	}
+
+	if len(rare) == 0 && len(near) == 0 && conflicting == 0 {
+		fmt.Println("No tagging problems found.")
+		return nil
+	}
+
+	if len(rare) > 0 {
+		fmt.Printf("Markers used on fewer than %d thought(s), possibly typos:\n", *minUses)
+		for _, mc := range rare {
+			action := fmt.Sprintf("review with: prothought search '#%s'", mc.Marker)
+			if to, ok := merge[mc.Marker]; ok {
+				action = fmt.Sprintf("prothought retag #%s #%s --merge", mc.Marker, to)
+			}
+			fmt.Printf("  #%-20s %d  → %s\n", mc.Marker, mc.Count, action)
+		}
+		fmt.Println()
+	}
+
+	if len(near) > 0 {
+		fmt.Printf("Markers at most %d edit(s) apart:\n", *maxDistance)
+		for _, p := range near {
+			fmt.Printf("  #%s (%d) ~ #%s (%d)  → prothought retag #%s #%s --merge\n",
+				p.from, uses[p.from], p.to, uses[p.to], p.from, p.to)
+		}
+		fmt.Println()
+	}
+
+	if len(found) > 0 {
+		fmt.Println("Thoughts with conflicting markers:")
+		for _, c := range found {
+			for _, id := range c.ids {
+				fmt.Printf("  #%s and #%s on thought %d  → prothought edit %d\n", c.a, c.b, id, id)
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("%d finding(s).\n", len(rare)+len(near)+conflicting)
+	return nil
+}
+
+// Find the live thoughts that carry both markers
+func thoughtsWithBothMarkers(db *sql.DB, a, b string) ([]int64, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT t.id
+		FROM thoughts t
+		INNER JOIN markers ma ON t.id = ma.thought_id
+		INNER JOIN markers mb ON t.id = mb.thought_id
+		WHERE t.deleted_at IS NULL AND ma.marker = ? AND mb.marker = ?
+		ORDER BY t.id`, a, b)
+	if err != nil {
+		return nil, fmt.Errorf("query conflicting markers: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan thought id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
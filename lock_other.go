@@ -0,0 +1,10 @@
+//go:build !unix
+
+package main
+
+import "fmt"
+
+// File locking relies on flock, which is only available on Unix systems
+func acquireLock(path string) (func(), error) {
+	return nil, fmt.Errorf("the lock setting is not supported on this platform")
+}
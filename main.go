@@ -1,12 +1,16 @@
 package main
 
 import (
+	"bytes"
 	"database/sql"
+	"flag"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -23,17 +27,26 @@ var (
 	commit  = "none"
 	date    = "unknown"
 
-	dbPath       string
-	hashtagRegex = regexp.MustCompile(`#([\w-]+)`)
+	homeDir       string
+	dbPath        string
+	defaultDBPath string
+	hashtagRegex  = regexp.MustCompile(`#([\p{L}\p{M}\p{N}_-]+)(?:=([\p{L}\p{M}\p{N}_-]+(?:\.[\p{L}\p{M}\p{N}_-]+)*))?`)
+	dayRangeRegex = regexp.MustCompile(`^(-?\d+)\.\.(-?\d+)$`)
 )
 
 func init() {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error getting home directory: %v\n", err)
-		os.Exit(1)
+		fail("getting home directory", err)
+	}
+	homeDir = home
+	defaultDBPath = filepath.Join(home, ".prothought.db")
+	dbPath = defaultDBPath
+
+	configPath = os.Getenv("PROTHOUGHT_CONFIG")
+	if configPath == "" {
+		configPath = filepath.Join(home, ".prothought.conf")
 	}
-	dbPath = filepath.Join(home, ".prothought.db")
 }
 
 // Database initialization
@@ -52,6 +65,25 @@ func initDB(db *sql.DB) error {
 		)`,
 		`CREATE INDEX IF NOT EXISTS idx_markers_thought_id ON markers(thought_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_markers_marker ON markers(marker)`,
+		`CREATE TABLE IF NOT EXISTS attachments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			thought_id INTEGER NOT NULL,
+			path TEXT NOT NULL,
+			FOREIGN KEY (thought_id) REFERENCES thoughts(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_attachments_thought_id ON attachments(thought_id)`,
+		`CREATE TABLE IF NOT EXISTS thought_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			thought_id INTEGER NOT NULL,
+			text TEXT NOT NULL,
+			replaced_at TEXT NOT NULL,
+			FOREIGN KEY (thought_id) REFERENCES thoughts(id) ON DELETE CASCADE
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_thought_history_thought_id ON thought_history(thought_id)`,
+		`CREATE TABLE IF NOT EXISTS templates (
+			name TEXT PRIMARY KEY,
+			body TEXT NOT NULL
+		)`,
 	}
 
 	for _, query := range queries {
@@ -60,9 +92,60 @@ func initDB(db *sql.DB) error {
 		}
 	}
 
+	// Columns added after the first release
+	if err := addColumnIfMissing(db, "markers", "value", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return fmt.Errorf("init db: %w", err)
+	}
+	if err := addColumnIfMissing(db, "thoughts", "deleted_at", "TEXT"); err != nil {
+		return fmt.Errorf("init db: %w", err)
+	}
+	if err := addColumnIfMissing(db, "thoughts", "mood", "INTEGER"); err != nil {
+		return fmt.Errorf("init db: %w", err)
+	}
+
+	return nil
+}
+
+// Add a column to an existing table unless it is already there
+func addColumnIfMissing(db *sql.DB, table, column, decl string) error {
+	rows, err := db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return fmt.Errorf("query columns of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return fmt.Errorf("scan column: %w", err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query columns of %s: %w", table, err)
+	}
+	rows.Close()
+
+	if _, err := db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, decl)); err != nil {
+		return fmt.Errorf("add column %s.%s: %w", table, column, err)
+	}
 	return nil
 }
 
+// Normalize a marker to its stored form. Markers are lowercased unless
+// case_sensitive is enabled, and lose their accents when strip_diacritics is.
+func normalizeMarker(tag string) string {
+	if cfg.enabled("strip_diacritics") {
+		tag = stripDiacritics(tag)
+	}
+	if cfg.enabled("case_sensitive") {
+		return tag
+	}
+	return strings.ToLower(tag)
+}
+
 // Extract hashtags from text
 func extractHashtags(text string) []string {
 	matches := hashtagRegex.FindAllStringSubmatch(text, -1)
@@ -71,7 +154,10 @@ func extractHashtags(text string) []string {
 
 	for _, match := range matches {
 		if len(match) > 1 {
-			tag := strings.ToLower(match[1])
+			tag := normalizeMarker(match[1])
+			if match[2] != "" {
+				tag += "=" + normalizeMarker(match[2])
+			}
 			if !seen[tag] {
 				seen[tag] = true
 				hashtags = append(hashtags, tag)
@@ -82,50 +168,247 @@ func extractHashtags(text string) []string {
 	return hashtags
 }
 
-// Log a thought with hashtags
-func logThought(db *sql.DB, text string) error {
-	ts := time.Now().Format(timestampFormat)
+// addOptions holds the flags accepted when logging a thought
+type addOptions struct {
+	deferMarkers bool
+	maxSize      int64 // reject thoughts larger than this many bytes; 0 means no limit
+	noWebhook    bool
+	mood         int // 1 to maxMood, or 0 for none
+	git          bool
+	emoji        bool
+	full         bool   // list every marker in the confirmation
+	at           string // timestamp to log at instead of now
+}
+
+// Markers listed in the log confirmation before the rest are summarized
+const confirmationMarkers = 5
+
+// Parse flags at the start of a thought. Only leading flags are recognized
+// so the thought text itself is never mistaken for options.
+func parseAddFlags(args []string) (addOptions, []string, error) {
+	var opts addOptions
+	for len(args) > 0 {
+		name, value, hasValue := strings.Cut(args[0], "=")
+		switch name {
+		case "--defer-markers":
+			opts.deferMarkers = true
+		case "--no-webhook":
+			opts.noWebhook = true
+		case "--emoji":
+			opts.emoji = true
+		case "--git":
+			opts.git = true
+		case "--full":
+			opts.full = true
+		case "--max-size":
+			if !hasValue {
+				if len(args) < 2 {
+					return opts, nil, fmt.Errorf("flag --max-size needs a value")
+				}
+				value, args = args[1], args[1:]
+			}
+			size, err := parseSize(value)
+			if err != nil {
+				return opts, nil, err
+			}
+			opts.maxSize = size
+		case "--mood":
+			if !hasValue {
+				if len(args) < 2 {
+					return opts, nil, fmt.Errorf("flag --mood needs a value")
+				}
+				value, args = args[1], args[1:]
+			}
+			mood, err := parseMood(value)
+			if err != nil {
+				return opts, nil, err
+			}
+			opts.mood = mood
+		default:
+			return opts, args, nil
+		}
+		args = args[1:]
+	}
+	return opts, args, nil
+}
+
+// Format the current time for storing, with the fractional seconds of
+// timestamp_precision so thoughts logged within a second keep their order
+func nowTimestamp() string {
+	layout := timestampFormat
+	switch cfg.get("timestamp_precision") {
+	case "ms":
+		layout += ".000"
+	case "us":
+		layout += ".000000"
+	case "ns":
+		layout += ".000000000"
+	}
+	return time.Now().Format(layout)
+}
 
-	result, err := db.Exec("INSERT INTO thoughts (timestamp, text) VALUES (?, ?)", ts, text)
+// Drop any fractional seconds from a stored timestamp
+func wholeSeconds(ts string) string {
+	if len(ts) > len(timestampFormat) {
+		return ts[:len(timestampFormat)]
+	}
+	return ts
+}
+
+// Save a thought with its markers and attachments, warning about
+// oversized text and notifying the webhook if one is configured
+func saveThought(db *sql.DB, text string, opts addOptions) (Thought, []string, []string, error) {
+	if opts.emoji || cfg.enabled("emoji") {
+		text = expandShortcodes(text)
+	}
+
+	// Oversized thoughts are usually an accidental paste
+	size := int64(len(text))
+	if opts.maxSize > 0 && size > opts.maxSize {
+		return Thought{}, nil, nil, fmt.Errorf("thought is %s, larger than --max-size %s", formatBytes(size), formatBytes(opts.maxSize))
+	}
+	if warnSize, _ := parseSize(cfg.get("warn_size")); warnSize > 0 && size > warnSize {
+		fmt.Fprintf(os.Stderr, "Warning: thought is %s (over warn_size %s); saving anyway\n", formatBytes(size), formatBytes(warnSize))
+	}
+
+	ts := opts.at
+	if ts == "" {
+		ts = nowTimestamp()
+	}
+
+	mood := sql.NullInt64{Int64: int64(opts.mood), Valid: opts.mood > 0}
+	result, err := db.Exec("INSERT INTO thoughts (timestamp, text, mood) VALUES (?, ?, ?)", ts, text, mood)
 	if err != nil {
-		return fmt.Errorf("insert thought: %w", err)
+		return Thought{}, nil, nil, fmt.Errorf("insert thought: %w", err)
 	}
 
 	thoughtID, err := result.LastInsertId()
 	if err != nil {
-		return fmt.Errorf("get last insert id: %w", err)
+		return Thought{}, nil, nil, fmt.Errorf("get last insert id: %w", err)
 	}
+	t := Thought{ID: thoughtID, Timestamp: ts, Text: text}
 
-	// Extract and save hashtags
-	hashtags := extractHashtags(text)
-	for _, tag := range hashtags {
-		if _, err := db.Exec("INSERT INTO markers (thought_id, marker) VALUES (?, ?)", thoughtID, tag); err != nil {
-			return fmt.Errorf("insert marker: %w", err)
+	// Extract and save hashtags, unless left for reindex-markers. The
+	// default marker and git context aren't in the text, so reindexing
+	// can't add them later.
+	var hashtags []string
+	if !opts.deferMarkers {
+		hashtags = extractHashtags(text)
+	}
+	hashtags = withDefaultMarker(hashtags)
+	if opts.git || cfg.enabled("git_context") {
+		if tags, ok := gitMarkers(); ok {
+			hashtags = appendMissingMarkers(hashtags, tags)
+		} else if opts.git {
+			fmt.Fprintln(os.Stderr, "Warning: not in a git repository with commits; saving without git context")
+		}
+	}
+	if err := insertMarkers(db, thoughtID, hashtags); err != nil {
+		return t, nil, nil, err
+	}
+
+	// Extract and save file references
+	attachments := extractAttachments(text)
+	if err := insertAttachments(db, thoughtID, attachments); err != nil {
+		return t, nil, nil, err
+	}
+
+	// Forwarding is best effort; the thought is already saved
+	if url := cfg.get("webhook_url"); url != "" && !opts.noWebhook {
+		if err := notifyWebhook(url, t, hashtags); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
 		}
 	}
 
-	// Print confirmation
+	return t, hashtags, attachments, nil
+}
+
+// Log a thought with hashtags
+func logThought(db *sql.DB, text string, opts addOptions) error {
+	t, hashtags, attachments, err := saveThought(db, text, opts)
+	if err != nil {
+		return err
+	}
+
+	// Print confirmation, keeping it to one readable line unless --full
 	markerInfo := ""
 	if len(hashtags) > 0 {
-		markerList := make([]string, len(hashtags))
-		for i, tag := range hashtags {
-			markerList[i] = "#" + tag
+		shown := hashtags
+		if !opts.full && len(shown) > confirmationMarkers {
+			shown = shown[:confirmationMarkers]
+		}
+		markerInfo = " with markers: " + joinMarkers(shown)
+		if more := len(hashtags) - len(shown); more > 0 {
+			markerInfo += fmt.Sprintf(" (+%d more)", more)
+		}
+	}
+	if opts.deferMarkers {
+		markerInfo += " (markers deferred)"
+	}
+	if len(attachments) > 0 {
+		markerInfo += fmt.Sprintf(" (%d attachment(s))", len(attachments))
+	}
+	if opts.mood > 0 {
+		markerInfo += fmt.Sprintf(" (mood %d)", opts.mood)
+	}
+	fmt.Printf("Saved thought at %s%s\n", wholeSeconds(t.Timestamp), markerInfo)
+
+	return nil
+}
+
+// Format markers as a comma-separated list of hashtags
+func joinMarkers(tags []string) string {
+	list := make([]string, len(tags))
+	for i, tag := range tags {
+		list[i] = "#" + tag
+	}
+	return strings.Join(list, ", ")
+}
+
+// Split a key=value marker into its key and value. A plain marker is a
+// key with an empty value.
+func splitMarker(tag string) (string, string) {
+	key, value, _ := strings.Cut(tag, "=")
+	return key, value
+}
+
+// Save the markers of a thought
+func insertMarkers(db execer, thoughtID int64, tags []string) error {
+	for _, tag := range tags {
+		key, value := splitMarker(tag)
+		if _, err := db.Exec("INSERT INTO markers (thought_id, marker, value) VALUES (?, ?, ?)", thoughtID, key, value); err != nil {
+			return fmt.Errorf("insert marker: %w", err)
 		}
-		markerInfo = " with markers: " + strings.Join(markerList, ", ")
 	}
-	fmt.Printf("Saved thought at %s%s\n", ts, markerInfo)
+	return nil
+}
 
+// Save the file references of a thought
+func insertAttachments(db execer, thoughtID int64, paths []string) error {
+	for _, path := range paths {
+		if _, err := db.Exec("INSERT INTO attachments (thought_id, path) VALUES (?, ?)", thoughtID, path); err != nil {
+			return fmt.Errorf("insert attachment: %w", err)
+		}
+	}
 	return nil
 }
 
 // Parse period arguments
 func parsePeriod(args []string) (string, string, error) {
-	today := time.Now()
+	return parsePeriodAt(args, time.Now())
+}
+
+// Parse period arguments relative to now
+func parsePeriodAt(args []string, now time.Time) (string, string, error) {
+	loc := periodLocation()
+	today := now.In(loc)
 	var startDate, endDate time.Time
 
-	key := "today"
+	var key string
 	if len(args) > 0 {
 		key = args[0]
+	} else {
+		key = cfg.get("default_period")
 	}
 
 	switch key {
@@ -141,7 +424,35 @@ func parsePeriod(args []string) (string, string, error) {
 	case "lastmonth", "last_month":
 		startDate = today.AddDate(0, 0, -29)
 		endDate = today
+	case "ytd":
+		startDate = time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, loc)
+		endDate = today
+	case "thisyear", "this_year":
+		startDate = time.Date(today.Year(), time.January, 1, 0, 0, 0, 0, loc)
+		endDate = time.Date(today.Year(), time.December, 31, 0, 0, 0, 0, loc)
+	case "lastyear", "last_year":
+		startDate = time.Date(today.Year()-1, time.January, 1, 0, 0, 0, 0, loc)
+		endDate = time.Date(today.Year()-1, time.December, 31, 0, 0, 0, 0, loc)
 	default:
+		if strings.HasPrefix(key, "last:") {
+			return "", "", fmt.Errorf("%s selects thoughts by count, not a time range, and cannot be used here", key)
+		}
+
+		// Day offsets from today, such as -7..0
+		if m := dayRangeRegex.FindStringSubmatch(key); m != nil {
+			from, errFrom := strconv.Atoi(m[1])
+			to, errTo := strconv.Atoi(m[2])
+			if errFrom != nil || errTo != nil {
+				return "", "", fmt.Errorf("invalid day range: %s", key)
+			}
+			if from > to {
+				return "", "", fmt.Errorf("invalid day range %s: %d is after %d", key, from, to)
+			}
+			startDate = today.AddDate(0, 0, from)
+			endDate = today.AddDate(0, 0, to)
+			break
+		}
+
 		// Try to parse as ISO date
 		parsedDate, err := time.Parse("2006-01-02", key)
 		if err != nil {
@@ -151,247 +462,1168 @@ func parsePeriod(args []string) (string, string, error) {
 		endDate = parsedDate
 	}
 
-	startTime := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, time.Local)
-	endTime := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, time.Local)
+	// Timestamps are stored in local time, so convert the zone's day
+	// boundaries back before comparing
+	startTime := time.Date(startDate.Year(), startDate.Month(), startDate.Day(), 0, 0, 0, 0, loc).In(time.Local)
+	endTime := time.Date(endDate.Year(), endDate.Month(), endDate.Day(), 23, 59, 59, 0, loc).In(time.Local)
 
 	return startTime.Format(timestampFormat), endTime.Format(timestampFormat), nil
 }
 
+// Get the zone in which period day boundaries are computed
+func periodLocation() *time.Location {
+	if cfg == nil || cfg.get("timezone") == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(cfg.get("timezone"))
+	if err != nil {
+		// Validated when the config was loaded
+		return time.Local
+	}
+	return loc
+}
+
 // Thought represents a thought record
 type Thought struct {
+	ID        int64
 	Timestamp string
 	Text      string
 }
 
-// Get thoughts for a period with optional marker filter
-func thoughtsForPeriod(db *sql.DB, periodArgs []string, marker string) ([]Thought, error) {
-	startTS, endTS, err := parsePeriod(periodArgs)
-	if err != nil {
-		return nil, err
-	}
+// Resolve period arguments into a query: a time range, or for last:N the
+// N most recent thoughts regardless of date
+func periodQuery(args []string) (thoughtQuery, error) {
+	return periodsQuery(args, false)
+}
 
-	var rows *sql.Rows
-	if marker != "" {
-		// Filter by marker
-		rows, err = db.Query(`
-			SELECT DISTINCT t.timestamp, t.text
-			FROM thoughts t
-			INNER JOIN markers m ON t.id = m.thought_id
-			WHERE t.timestamp BETWEEN ? AND ?
-			  AND m.marker = ?
-			ORDER BY t.timestamp ASC`,
-			startTS, endTS, strings.ToLower(marker))
+// Resolve one or more period arguments into a query. Several periods select
+// the thoughts in any of them; two dates are a range unless discrete is set.
+func periodsQuery(args []string, discrete bool) (thoughtQuery, error) {
+	var key string
+	if len(args) > 0 {
+		key = args[0]
 	} else {
-		rows, err = db.Query(`
-			SELECT timestamp, text
-			FROM thoughts
-			WHERE timestamp BETWEEN ? AND ?
-			ORDER BY timestamp ASC`,
-			startTS, endTS)
+		key = cfg.get("default_period")
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("query thoughts: %w", err)
+	for _, arg := range args[min(1, len(args)):] {
+		if strings.HasPrefix(arg, "last:") {
+			return thoughtQuery{}, fmt.Errorf("%s cannot be combined with other periods", arg)
+		}
 	}
-	defer rows.Close()
-
-	var thoughts []Thought
-	for rows.Next() {
-		var t Thought
-		if err := rows.Scan(&t.Timestamp, &t.Text); err != nil {
-			return nil, fmt.Errorf("scan thought: %w", err)
+	if strings.HasPrefix(key, "last:") {
+		if len(args) > 1 {
+			return thoughtQuery{}, fmt.Errorf("%s cannot be combined with other periods", key)
 		}
-		thoughts = append(thoughts, t)
+		n, err := strconv.Atoi(strings.TrimPrefix(key, "last:"))
+		if err != nil || n <= 0 {
+			return thoughtQuery{}, fmt.Errorf("invalid thought count in %s", key)
+		}
+		return thoughtQuery{limit: n}, nil
 	}
 
-	return thoughts, rows.Err()
-}
-
-// List thoughts for a period
-func listThoughts(db *sql.DB, periodArgs []string, marker string) error {
-	thoughts, err := thoughtsForPeriod(db, periodArgs, marker)
+	ranges, err := parsePeriods(append([]string{key}, args[min(1, len(args)):]...), discrete)
 	if err != nil {
-		return err
+		return thoughtQuery{}, err
 	}
+	return thoughtQuery{start: ranges[0][0], end: ranges[0][1], also: ranges[1:]}, nil
+}
 
-	if len(thoughts) == 0 {
-		markerMsg := ""
-		if marker != "" {
-			markerMsg = fmt.Sprintf(" with marker #%s", marker)
+// Parse several period arguments into their start and end timestamps. Two
+// YYYY-MM-DD dates are read as the range between them unless discrete is
+// set, in which case, as for any other list, each is a period of its own.
+func parsePeriods(args []string, discrete bool) ([][2]string, error) {
+	if len(args) == 2 && !discrete && isDate(args[0]) && isDate(args[1]) {
+		start, _, err := parsePeriod(args[:1])
+		if err != nil {
+			return nil, err
 		}
-		fmt.Printf("No thoughts found for that period%s.\n", markerMsg)
-		return nil
+		_, end, err := parsePeriod(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		if end < start {
+			return nil, fmt.Errorf("%s is before %s; pass --discrete for the two days only", args[1], args[0])
+		}
+		return [][2]string{{start, end}}, nil
 	}
 
-	for _, t := range thoughts {
-		fmt.Printf("[%s] %s\n", t.Timestamp, t.Text)
+	var ranges [][2]string
+	for _, arg := range args {
+		start, end, err := parsePeriod([]string{arg})
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, [2]string{start, end})
 	}
-
-	return nil
+	return ranges, nil
 }
 
-// Strike through the last thought
-func strikeLastThought(db *sql.DB) error {
-	var id int64
-	var ts, text string
+// Report whether s is a YYYY-MM-DD date
+func isDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
 
-	err := db.QueryRow(`
-		SELECT id, timestamp, text
-		FROM thoughts
-		ORDER BY timestamp DESC, id DESC
-		LIMIT 1`).Scan(&id, &ts, &text)
+// Parse a time of day given as HH:MM or HH:MM:SS
+func parseClock(s string) (time.Time, error) {
+	for _, layout := range []string{"15:04", "15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected HH:MM or HH:MM:SS", s)
+}
 
-	if err == sql.ErrNoRows {
-		fmt.Println("No thoughts to strike through.")
+// Narrow a period to start at after on its first day and end before before
+// on its last day. Either bound may be empty.
+func applyTimeBounds(q *thoughtQuery, after, before string) error {
+	if after == "" && before == "" {
 		return nil
 	}
-	if err != nil {
-		return fmt.Errorf("query last thought: %w", err)
+	if q.start == "" || q.end == "" {
+		return fmt.Errorf("--after and --before need a time period, not last:N")
 	}
-
-	// Check if already struck through
-	if strings.HasPrefix(text, "~~") && strings.HasSuffix(text, "~~") {
-		fmt.Println("Last thought is already marked as nvm.")
-		return nil
+	if len(q.also) > 0 {
+		return fmt.Errorf("--after and --before need a single period")
 	}
 
-	newText := "~~" + text + "~~"
-	if _, err := db.Exec("UPDATE thoughts SET text = ? WHERE id = ?", newText, id); err != nil {
-		return fmt.Errorf("update thought: %w", err)
+	loc := periodLocation()
+	start, _ := time.ParseInLocation(timestampFormat, q.start, time.Local)
+	end, _ := time.ParseInLocation(timestampFormat, q.end, time.Local)
+	start, end = start.In(loc), end.In(loc)
+	if after != "" {
+		c, err := parseClock(after)
+		if err != nil {
+			return err
+		}
+		start = time.Date(start.Year(), start.Month(), start.Day(), c.Hour(), c.Minute(), c.Second(), 0, loc)
+	}
+	if before != "" {
+		c, err := parseClock(before)
+		if err != nil {
+			return err
+		}
+		// --before is exclusive, the stored bound inclusive
+		end = time.Date(end.Year(), end.Month(), end.Day(), c.Hour(), c.Minute(), c.Second(), 0, loc).Add(-time.Second)
+	}
+	if !start.Before(end) {
+		return fmt.Errorf("--after must be earlier than --before")
 	}
 
-	fmt.Printf("Marked last thought from %s as nvm.\n", ts)
+	q.start, q.end = start.In(time.Local).Format(timestampFormat), end.In(time.Local).Format(timestampFormat)
 	return nil
 }
 
-
-// Parse arguments with marker
-func parseArgsWithMarker(args []string) ([]string, string) {
-	var periodArgs []string
-	var marker string
-
-	for _, arg := range args {
-		if strings.HasPrefix(arg, "#") {
-			marker = strings.TrimPrefix(arg, "#")
-		} else {
-			periodArgs = append(periodArgs, arg)
-		}
+// Get thoughts for a period with optional marker filter
+func thoughtsForPeriod(db *sql.DB, periodArgs []string, marker string) ([]Thought, error) {
+	q, err := periodQuery(periodArgs)
+	if err != nil {
+		return nil, err
 	}
+	q.marker = marker
 
-	return periodArgs, marker
+	return queryThoughts(db, q)
 }
 
-// Copy a file from src to dst
-func copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
-	if err != nil {
-		return fmt.Errorf("open source file: %w", err)
+// Load the markers of the given thoughts, keyed by thought id
+func markersForThoughts(db *sql.DB, thoughts []Thought) (map[int64][]string, error) {
+	markers := make(map[int64][]string)
+	if len(thoughts) == 0 {
+		return markers, nil
+	}
+
+	placeholders := make([]string, len(thoughts))
+	args := make([]interface{}, len(thoughts))
+	for i, t := range thoughts {
+		placeholders[i] = "?"
+		args[i] = t.ID
 	}
-	defer sourceFile.Close()
 
-	destFile, err := os.Create(dst)
+	rows, err := db.Query(`
+		SELECT thought_id, marker, value
+		FROM markers
+		WHERE thought_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY id ASC`, args...)
 	if err != nil {
-		return fmt.Errorf("create destination file: %w", err)
+		return nil, fmt.Errorf("query markers: %w", err)
 	}
-	defer destFile.Close()
+	defer rows.Close()
 
-	if _, err := io.Copy(destFile, sourceFile); err != nil {
-		return fmt.Errorf("copy file: %w", err)
+	for rows.Next() {
+		var id int64
+		var marker, value string
+		if err := rows.Scan(&id, &marker, &value); err != nil {
+			return nil, fmt.Errorf("scan marker: %w", err)
+		}
+		if value != "" {
+			marker += "=" + value
+		}
+		markers[id] = append(markers[id], marker)
 	}
 
-	return nil
+	return markers, rows.Err()
 }
 
-// Copy skills from .agents/skills to ~/.claude/skills
-func initSkills() error {
-	// Get current working directory
-	cwd, err := os.Getwd()
+// Format a stored timestamp using the configured time_format, or relative
+// to now when relative_time is enabled
+func displayTime(ts string) string {
+	layout := cfg.get("time_format")
+	relative := cfg.enabled("relative_time")
+	if layout == timestampFormat && !relative {
+		return wholeSeconds(ts)
+	}
+	t, err := time.ParseInLocation(timestampFormat, ts, time.Local)
 	if err != nil {
-		return fmt.Errorf("get working directory: %w", err)
+		return ts
 	}
-
-	// Check if .agents/skills exists
-	agentsSkillsDir := filepath.Join(cwd, ".agents", "skills")
-	if _, err := os.Stat(agentsSkillsDir); os.IsNotExist(err) {
-		return fmt.Errorf("no .agents/skills directory found in current directory")
+	if relative {
+		return relativeTime(t, time.Now())
 	}
+	return t.Format(layout)
+}
 
-	// Get user's home directory
-	home, err := os.UserHomeDir()
-	if err != nil {
-		return fmt.Errorf("get home directory: %w", err)
+// Describe t relative to now, such as "2h ago" or "yesterday 14:03".
+// Anything older than a week, or in the future, is shown as a date.
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	day := func(t time.Time) time.Time { return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()) }
+	days := int(day(now).Sub(day(t)).Hours() / 24)
+
+	switch {
+	case d < 0:
+		return t.Format("2006-01-02 15:04")
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case days == 0:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	case days == 1:
+		return "yesterday " + t.Format("15:04")
+	case days < 7:
+		return t.Format("Monday 15:04")
 	}
+	return t.Format("2006-01-02 15:04")
+}
 
-	// Target directory
-	claudeSkillsDir := filepath.Join(home, ".claude", "skills")
-
-	// Ensure ~/.claude/skills exists
-	if err := os.MkdirAll(claudeSkillsDir, 0755); err != nil {
-		return fmt.Errorf("create claude skills directory: %w", err)
+// Print thought ids, one per line, for piping into other commands
+func printIDs(w io.Writer, thoughts []Thought) {
+	for _, t := range thoughts {
+		fmt.Fprintln(w, t.ID)
 	}
+}
 
-	// Read all skill directories
-	entries, err := os.ReadDir(agentsSkillsDir)
-	if err != nil {
-		return fmt.Errorf("read skills directory: %w", err)
-	}
+// Print thoughts as a Markdown table with their markers
+func printMarkdownTable(w io.Writer, thoughts []Thought, markers map[int64][]string) {
+	// Pipes would end the cell and newlines the row
+	cell := strings.NewReplacer(`|`, `\|`, "\r\n", "<br>", "\n", "<br>")
 
-	copiedCount := 0
-	for _, entry := range entries {
-		if !entry.IsDir() {
-			continue
+	fmt.Fprintln(w, "| Time | Thought | Tags |")
+	fmt.Fprintln(w, "|------|---------|------|")
+	for _, t := range thoughts {
+		tags := make([]string, len(markers[t.ID]))
+		for i, m := range markers[t.ID] {
+			tags[i] = "#" + m
 		}
+		fmt.Fprintf(w, "| %s | %s | %s |\n",
+			cell.Replace(displayTime(t.Timestamp)), cell.Replace(strings.TrimSpace(t.Text)), cell.Replace(strings.Join(tags, " ")))
+	}
+}
 
-		skillName := entry.Name()
-		srcSkillDir := filepath.Join(agentsSkillsDir, skillName)
-		dstSkillDir := filepath.Join(claudeSkillsDir, skillName)
+// Keep thoughts whose word count is within the limits; 0 means no limit
+func filterByWordCount(thoughts []Thought, minWords, maxWords int) []Thought {
+	if minWords == 0 && maxWords == 0 {
+		return thoughts
+	}
 
-		// Create destination skill directory
-		if err := os.MkdirAll(dstSkillDir, 0755); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not create directory for skill '%s': %v\n", skillName, err)
+	var kept []Thought
+	for _, t := range thoughts {
+		n := len(strings.Fields(t.Text))
+		if n < minWords || (maxWords > 0 && n > maxWords) {
 			continue
 		}
+		kept = append(kept, t)
+	}
+	return kept
+}
 
-		// Copy all files in the skill directory
-		skillFiles, err := os.ReadDir(srcSkillDir)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not read skill '%s': %v\n", skillName, err)
-			continue
+// List thoughts for a period to w
+func listThoughts(db *sql.DB, w io.Writer, periodArgs []string, marker string, opts listOptions) error {
+	var q thoughtQuery
+	var err error
+	// Polling by id shouldn't stop at the default period
+	if opts.sinceID == 0 || len(periodArgs) > 0 {
+		if q, err = periodsQuery(periodArgs, opts.discrete); err != nil {
+			return err
 		}
+	}
+	q.after = opts.sinceID
+	q.marker = marker
+	q.struck = opts.struck
+	q.untagged = opts.untagged
+	q.exclude = opts.exclude
+	if err := applyTimeBounds(&q, opts.after, opts.before); err != nil {
+		return err
+	}
+	if opts.explain {
+		return explainQuery(db, q)
+	}
+	if opts.printSQL {
+		printSQL(q.build())
+		return nil
+	}
+	if opts.countMarkers {
+		return printMarkerCounts(db, w, q)
+	}
 
-		for _, file := range skillFiles {
-			if file.IsDir() {
-				continue
-			}
+	thoughts, err := queryThoughts(db, q)
+	if err != nil {
+		return err
+	}
+	thoughts = filterByWordCount(thoughts, opts.minWords, opts.maxWords)
 
-			srcFile := filepath.Join(srcSkillDir, file.Name())
-			dstFile := filepath.Join(dstSkillDir, file.Name())
+	if opts.onlyIDs {
+		printIDs(w, thoughts)
+		return nil
+	}
 
-			if err := copyFile(srcFile, dstFile); err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: could not copy %s: %v\n", file.Name(), err)
-				continue
-			}
+	if opts.storedMarkers {
+		markers, err := markersForThoughts(db, thoughts)
+		if err != nil {
+			return err
+		}
+		for i, t := range thoughts {
+			thoughts[i].Text = useStoredMarkers(t.Text, markers[t.ID])
 		}
+	}
 
-		fmt.Printf("✓ Copied skill: %s\n", skillName)
-		copiedCount++
+	if opts.template != "" || opts.templateFile != "" {
+		tmpl, err := loadTemplate(opts.template, opts.templateFile)
+		if err != nil {
+			return err
+		}
+		return renderTemplate(w, tmpl, thoughts)
+	}
+
+	if len(thoughts) == 0 && opts.untagged {
+		fmt.Fprintln(w, "No untagged thoughts for that period.")
+		return nil
+	}
+	if len(thoughts) == 0 {
+		markerMsg := ""
+		if marker != "" {
+			// An unknown marker is most likely a typo
+			key, _ := splitMarker(marker)
+			suggestions, err := suggestMarkers(db, key, 2)
+			if err != nil {
+				return err
+			}
+			if len(suggestions) > 0 {
+				fmt.Fprintf(w, "No thoughts with #%s; %s\n", marker, didYouMean(suggestions))
+				return nil
+			}
+			markerMsg = fmt.Sprintf(" with marker #%s", marker)
+		}
+		fmt.Fprintf(w, "No thoughts found for that period%s.\n", markerMsg)
+		return nil
+	}
+
+	if opts.markdownTable {
+		markers, err := markersForThoughts(db, thoughts)
+		if err != nil {
+			return err
+		}
+		printMarkdownTable(w, thoughts, markers)
+		return nil
+	}
+
+	var attachments map[int64][]string
+	if opts.checkFiles {
+		if attachments, err = attachmentsForThoughts(db, thoughts); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range thoughts {
+		if opts.sinceID > 0 {
+			// Callers remember the last id to poll from next time
+			fmt.Fprintf(w, "%d  ", t.ID)
+		}
+		fmt.Fprintf(w, "%s%s\n", formatThought(t), missingFilesNote(attachments[t.ID]))
+	}
+
+	return nil
+}
+
+// Print a table of markers and how many thoughts carry each, with a total
+func printMarkerCounts(db *sql.DB, w io.Writer, q thoughtQuery) error {
+	counts, err := countMarkersForQuery(db, q)
+	if err != nil {
+		return err
+	}
+	if len(counts) == 0 {
+		fmt.Fprintln(w, "No markers used in that period.")
+		return nil
+	}
+
+	total := 0
+	fmt.Fprintf(w, "%-21s %s\n", "MARKER", "THOUGHTS")
+	for _, mc := range counts {
+		fmt.Fprintf(w, "#%-20s %d\n", mc.Marker, mc.Count)
+		total += mc.Count
+	}
+	fmt.Fprintf(w, "%-21s %d\n", "total", total)
+	return nil
+}
+
+// Get the text placed before and after a struck thought, from the
+// strike_format setting
+func strikeTokens() (string, string) {
+	prefix, suffix, _ := strings.Cut(cfg.get("strike_format"), "%s")
+	return prefix, suffix
+}
+
+// Report whether a thought's text is struck through
+func isStruck(text string) bool {
+	prefix, suffix := strikeTokens()
+	return strings.HasPrefix(text, prefix) && strings.HasSuffix(text, suffix)
+}
+
+// Mark text as struck through
+func strikeText(text string) string {
+	prefix, suffix := strikeTokens()
+	return prefix + text + suffix
+}
+
+// strikeOptions holds the flags accepted by nvm
+type strikeOptions struct {
+	confirm bool
+	yes     bool
+}
+
+// Parse nvm flags, returning the remaining arguments
+func parseStrikeFlags(cmd string, args []string) (strikeOptions, []string, error) {
+	var opts strikeOptions
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.confirm, "confirm", false, "ask before striking")
+	fs.BoolVar(&opts.yes, "yes", false, "never ask for confirmation")
+
+	rest, err := parseFlags(fs, args)
+	return opts, rest, err
+}
+
+// execer is implemented by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Wrap a thought's text in strikethrough
+func strikeThought(db execer, id int64, text string) error {
+	if _, err := db.Exec("UPDATE thoughts SET text = ? WHERE id = ?", strikeText(text), id); err != nil {
+		return fmt.Errorf("update thought: %w", err)
+	}
+	return nil
+}
+
+// Strike through the last thought
+func strikeLastThought(db *sql.DB, opts strikeOptions) error {
+	var id int64
+	var ts, text string
+
+	err := db.QueryRow(`
+		SELECT id, timestamp, text
+		FROM thoughts
+		WHERE deleted_at IS NULL
+		ORDER BY timestamp DESC, id DESC
+		LIMIT 1`).Scan(&id, &ts, &text)
+
+	if err == sql.ErrNoRows {
+		fmt.Println("No thoughts to strike through.")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("query last thought: %w", err)
+	}
+
+	// Check if already struck through
+	if isStruck(text) {
+		fmt.Println("Last thought is already marked as nvm.")
+		return nil
+	}
+
+	// Only prompt when someone is there to answer
+	if opts.confirm && !opts.yes && isTerminal(os.Stdin) {
+		fmt.Printf("[%s] %s\n", ts, text)
+		ok, err := confirm("Strike this thought?")
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Println("Aborted.")
+			return nil
+		}
+	}
+
+	ok, err := confirmProtected(db, "strike", "id = ?", id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	if err := strikeThought(db, id, text); err != nil {
+		return err
+	}
+
+	fmt.Printf("Marked last thought from %s as nvm.\n", ts)
+	return nil
+}
+
+// Strike through a thought by id
+func strikeThoughtByID(db *sql.DB, arg string) error {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid thought id: %s", arg)
+	}
+
+	var ts, text string
+	err = db.QueryRow("SELECT timestamp, text FROM thoughts WHERE id = ? AND deleted_at IS NULL", id).Scan(&ts, &text)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no thought with id %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("query thought: %w", err)
+	}
+
+	if isStruck(text) {
+		fmt.Printf("Thought %d is already marked as nvm.\n", id)
+		return nil
+	}
+	ok, err := confirmProtected(db, "strike", "id = ?", id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+	if err := strikeThought(db, id, text); err != nil {
+		return err
+	}
+
+	fmt.Printf("Marked thought %d from %s as nvm.\n", id, ts)
+	return nil
+}
+
+// Strike through every thought carrying a marker
+func strikeByMarker(db *sql.DB, marker string, opts strikeOptions) error {
+	thoughts, err := queryThoughts(db, thoughtQuery{marker: marker})
+	if err != nil {
+		return err
+	}
+
+	var pending []Thought
+	for _, t := range thoughts {
+		if !isStruck(t.Text) {
+			pending = append(pending, t)
+		}
+	}
+	skipped := len(thoughts) - len(pending)
+
+	if len(pending) == 0 {
+		fmt.Printf("No thoughts to strike with marker #%s (%d already marked as nvm).\n", marker, skipped)
+		return nil
+	}
+
+	ok, err := confirmBulk(len(pending), fmt.Sprintf("strike %d thought(s) with marker #%s", len(pending), marker), opts.yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+	ids := make([]int64, len(pending))
+	for i, t := range pending {
+		ids[i] = t.ID
+	}
+	cond, condArgs := idsCondition(ids)
+	ok, err = confirmProtected(db, "strike", cond, condArgs...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, t := range pending {
+		if err := strikeThought(tx, t.ID, t.Text); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Printf("Marked %d thought(s) with marker #%s as nvm (%d already marked).\n", len(pending), marker, skipped)
+	return nil
+}
+
+// listOptions holds the flags accepted by summarize
+type listOptions struct {
+	template      string
+	templateFile  string
+	checkFiles    bool
+	minWords      int
+	maxWords      int
+	onlyIDs       bool
+	explain       bool
+	printSQL      bool
+	struck        struckFilter
+	noPager       bool
+	countMarkers  bool
+	after         string
+	before        string
+	untagged      bool
+	discrete      bool
+	markdownTable bool
+	sinceID       int64
+	exclude       stringList
+	storedMarkers bool
+}
+
+// Parse summarize flags, returning the remaining period and marker arguments
+func parseListFlags(cmd string, args []string) (listOptions, []string, error) {
+	var opts listOptions
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.StringVar(&opts.template, "template", "", "text/template applied to each thought")
+	fs.StringVar(&opts.templateFile, "template-file", "", "file containing an output template")
+	fs.BoolVar(&opts.checkFiles, "check-files", false, "flag attachments whose files no longer exist")
+	fs.IntVar(&opts.minWords, "min-words", 0, "only thoughts with at least this many words")
+	fs.IntVar(&opts.maxWords, "max-words", 0, "only thoughts with at most this many words")
+	fs.BoolVar(&opts.onlyIDs, "only-ids", false, "print only the ids of matching thoughts")
+	fs.BoolVar(&opts.explain, "explain", false, "print the query plan instead of results")
+	fs.BoolVar(&opts.printSQL, "print-sql", false, "print the SQL and its parameters to stderr instead of running it")
+	struck := fs.Bool("struck", false, "only thoughts that are struck through")
+	kept := fs.Bool("not-struck", false, "only thoughts that are not struck through")
+	fs.BoolVar(&opts.noPager, "no-pager", false, "never pipe output through $PAGER")
+	fs.BoolVar(&opts.countMarkers, "count-by-marker", false, "print how many thoughts carry each marker")
+	fs.StringVar(&opts.after, "after", "", "only thoughts at or after this time of day (HH:MM)")
+	fs.StringVar(&opts.before, "before", "", "only thoughts before this time of day (HH:MM)")
+	fs.BoolVar(&opts.discrete, "discrete", false, "treat two dates as separate days rather than a range")
+	fs.BoolVar(&opts.markdownTable, "markdown-table", false, "print a Markdown table of time, thought and tags")
+	fs.Int64Var(&opts.sinceID, "since-id", 0, "only thoughts with a greater id, each printed with its id")
+	fs.Var(&opts.exclude, "exclude", "hide thoughts carrying this marker (repeatable)")
+	fs.BoolVar(&opts.storedMarkers, "use-stored-markers", false, "show the stored markers in place of the hashtags in the text")
+
+	parsed, err := parseFlags(fs, args)
+	if err != nil {
+		return opts, nil, err
+	}
+	// -#marker is shorthand for --exclude #marker
+	var rest []string
+	for _, arg := range parsed {
+		if strings.HasPrefix(arg, "-#") {
+			opts.exclude = append(opts.exclude, arg[1:])
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	for i, m := range opts.exclude {
+		opts.exclude[i] = strings.TrimPrefix(m, "#")
+		if opts.exclude[i] == "" {
+			return opts, nil, fmt.Errorf("--exclude needs a marker")
+		}
+	}
+	if opts.struck, err = parseStruckFilter(*struck, *kept); err != nil {
+		return opts, nil, err
+	}
+	if opts.sinceID < 0 {
+		return opts, nil, fmt.Errorf("--since-id cannot be negative")
+	}
+	if opts.minWords < 0 || opts.maxWords < 0 {
+		return opts, nil, fmt.Errorf("word limits cannot be negative")
+	}
+	if opts.maxWords > 0 && opts.minWords > opts.maxWords {
+		return opts, nil, fmt.Errorf("--min-words cannot be greater than --max-words")
+	}
+	if opts.markdownTable && (opts.onlyIDs || opts.template != "" || opts.templateFile != "") {
+		return opts, nil, fmt.Errorf("--markdown-table cannot be combined with --only-ids or templates")
+	}
+	if opts.countMarkers && (opts.minWords > 0 || opts.maxWords > 0) {
+		return opts, nil, fmt.Errorf("--count-by-marker cannot be combined with word limits")
+	}
+	return opts, rest, nil
+}
+
+// Report whether an argument starting with a dash is a positional rather
+// than a flag: day ranges such as -7..0 and excluded markers like -#work
+func dashPositional(arg string) bool {
+	return dayRangeRegex.MatchString(arg) || strings.HasPrefix(arg, "-#")
+}
+
+// Parse flags allowing them to be interleaved with positional arguments
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for len(args) > 0 {
+		if dashPositional(args[0]) {
+			positional = append(positional, args[0])
+			args = args[1:]
+			continue
+		}
+		end := len(args)
+		for i, arg := range args {
+			if dashPositional(arg) {
+				end = i
+				break
+			}
+		}
+
+		if err := fs.Parse(args[:end]); err != nil {
+			return nil, err
+		}
+		parsed := fs.Args()
+		if len(parsed) > 0 {
+			positional = append(positional, parsed[0])
+			parsed = parsed[1:]
+		}
+		args = append(append([]string{}, parsed...), args[end:]...)
+	}
+	return positional, nil
+}
+
+// Parse arguments with marker
+func parseArgsWithMarker(args []string) ([]string, string) {
+	var periodArgs []string
+	var marker string
+
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "#") {
+			marker = strings.TrimPrefix(arg, "#")
+		} else {
+			periodArgs = append(periodArgs, arg)
+		}
+	}
+
+	return periodArgs, marker
+}
+
+// Copy a file from src to dst
+func copyFile(src, dst string) error {
+	sourceFile, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("open source file: %w", err)
+	}
+	defer sourceFile.Close()
+
+	info, err := sourceFile.Stat()
+	if err != nil {
+		return fmt.Errorf("stat source file: %w", err)
+	}
+
+	destFile, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("create destination file: %w", err)
+	}
+	defer destFile.Close()
+
+	if _, err := io.Copy(destFile, sourceFile); err != nil {
+		return fmt.Errorf("copy file: %w", err)
+	}
+
+	// Preserve permissions of files that already existed
+	if err := destFile.Chmod(info.Mode().Perm()); err != nil {
+		return fmt.Errorf("set permissions: %w", err)
+	}
+
+	// Keep the source modification time so later runs can spot local edits
+	if err := os.Chtimes(dst, info.ModTime(), info.ModTime()); err != nil {
+		return fmt.Errorf("set modification time: %w", err)
+	}
+
+	return nil
+}
+
+// skillsOptions holds the flags accepted by init-skills
+type skillsOptions struct {
+	force  bool
+	link   bool
+	dryRun bool
+	from   string
+	to     string
+}
+
+// Parse init-skills flags
+func parseSkillsFlags(cmd string, args []string) (skillsOptions, error) {
+	var opts skillsOptions
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.force, "force", false, "overwrite existing skill files")
+	fs.BoolVar(&opts.link, "link", false, "symlink skills instead of copying them")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "show what would be copied without writing")
+	fs.StringVar(&opts.from, "from", "", "directory to copy skills from")
+	fs.StringVar(&opts.to, "to", "", "directory to copy skills to")
+
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return opts, err
+	}
+	if len(rest) > 0 {
+		return opts, fmt.Errorf("unexpected argument: %s", rest[0])
+	}
+	return opts, nil
+}
+
+// Resolve the skills source and destination from flags, environment
+// (PROTHOUGHT_SKILLS_FROM, PROTHOUGHT_SKILLS_TO) or defaults
+func resolveSkillsDirs(opts skillsOptions) (string, string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", "", fmt.Errorf("get home directory: %w", err)
+	}
+
+	src := firstNonEmpty(opts.from, os.Getenv("PROTHOUGHT_SKILLS_FROM"))
+	if src == "" {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return "", "", fmt.Errorf("get working directory: %w", err)
+		}
+		src = filepath.Join(cwd, ".agents", "skills")
+	}
+
+	dst := firstNonEmpty(opts.to, os.Getenv("PROTHOUGHT_SKILLS_TO"))
+	if dst == "" {
+		dst = filepath.Join(home, ".claude", "skills")
+	}
+
+	return expandHome(src, home), expandHome(dst, home), nil
+}
+
+// Return the first non-empty string
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// Expand a leading ~ to the home directory
+func expandHome(path, home string) string {
+	if path == "~" {
+		return home
+	}
+	if strings.HasPrefix(path, "~/") {
+		return filepath.Join(home, path[2:])
+	}
+	return path
+}
+
+// Copy skills from the source directory (default .agents/skills) to the
+// destination directory (default ~/.claude/skills)
+func initSkills(opts skillsOptions) error {
+	srcDir, dstDir, err := resolveSkillsDirs(opts)
+	if err != nil {
+		return err
+	}
+
+	// Validate the source directory
+	info, err := os.Stat(srcDir)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("skills directory not found: %s", srcDir)
+	}
+	if err != nil {
+		return fmt.Errorf("stat skills directory: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("skills source is not a directory: %s", srcDir)
+	}
+
+	// Ensure the destination exists and is a directory
+	if info, err := os.Stat(dstDir); err == nil && !info.IsDir() {
+		return fmt.Errorf("skills destination is not a directory: %s", dstDir)
+	}
+	if !opts.dryRun {
+		if err := os.MkdirAll(dstDir, 0755); err != nil {
+			return fmt.Errorf("create skills destination directory: %w", err)
+		}
+	}
+
+	// Read all skill directories
+	entries, err := os.ReadDir(srcDir)
+	if err != nil {
+		return fmt.Errorf("read skills directory: %w", err)
+	}
+
+	copiedCount := 0
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		skillName := entry.Name()
+		srcSkillDir := filepath.Join(srcDir, skillName)
+		dstSkillDir := filepath.Join(dstDir, skillName)
+
+		if opts.link {
+			linked, err := linkSkill(srcSkillDir, dstSkillDir, opts)
+			if err == nil {
+				if linked && opts.dryRun {
+					fmt.Printf("  %s -> %s (link)\n", dstSkillDir, srcSkillDir)
+				} else if linked {
+					fmt.Printf("✓ Linked skill: %s -> %s\n", skillName, srcSkillDir)
+				} else {
+					fmt.Printf("  skipped (exists): %s\n", skillName)
+				}
+				copiedCount++
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "Warning: could not link skill '%s', copying instead: %v\n", skillName, err)
+		}
+
+		// A linked skill points back at the source, so copying into it
+		// would truncate the originals
+		if fi, err := os.Lstat(dstSkillDir); err == nil && fi.Mode()&os.ModeSymlink != 0 {
+			if !opts.force {
+				fmt.Printf("  skipped (linked): %s\n", skillName)
+				copiedCount++
+				continue
+			}
+			if opts.dryRun {
+				fmt.Printf("  remove link: %s\n", dstSkillDir)
+			} else if err := os.Remove(dstSkillDir); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not remove link for skill '%s': %v\n", skillName, err)
+				continue
+			}
+		}
+
+		// Create destination skill directory
+		if !opts.dryRun {
+			if err := os.MkdirAll(dstSkillDir, 0755); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not create directory for skill '%s': %v\n", skillName, err)
+				continue
+			}
+		}
+
+		copied, skipped, err := copySkill(skillName, srcSkillDir, dstSkillDir, opts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not read skill '%s': %v\n", skillName, err)
+			continue
+		}
+
+		summary := fmt.Sprintf("%d file(s) copied", copied)
+		if skipped > 0 {
+			summary += fmt.Sprintf(", %d skipped", skipped)
+		}
+		if opts.dryRun {
+			fmt.Printf("Would copy skill: %s (%s)\n", skillName, summary)
+		} else {
+			fmt.Printf("✓ Copied skill: %s (%s)\n", skillName, summary)
+		}
+		copiedCount++
 	}
 
 	if copiedCount == 0 {
 		return fmt.Errorf("no skills found to copy")
 	}
 
-	fmt.Printf("\nSuccessfully copied %d skill(s) to %s\n", copiedCount, claudeSkillsDir)
+	verb := "copied"
+	if opts.link {
+		verb = "linked"
+	}
+	if opts.dryRun {
+		fmt.Printf("\nDry run: %d skill(s) would be %s to %s, nothing was written\n", copiedCount, verb, dstDir)
+		return nil
+	}
+	fmt.Printf("\nSuccessfully %s %d skill(s) to %s\n", verb, copiedCount, dstDir)
 	return nil
 }
 
+// Symlink a skill directory into the destination. Returns false when the
+// destination already exists and force is not set.
+func linkSkill(src, dst string, opts skillsOptions) (bool, error) {
+	absSrc, err := filepath.Abs(src)
+	if err != nil {
+		return false, fmt.Errorf("resolve source path: %w", err)
+	}
+
+	if _, err := os.Lstat(dst); err == nil {
+		if target, err := os.Readlink(dst); err == nil && target == absSrc {
+			return true, nil
+		}
+		if !opts.force {
+			return false, nil
+		}
+		if opts.dryRun {
+			return true, nil
+		}
+		if err := os.RemoveAll(dst); err != nil {
+			return false, fmt.Errorf("remove existing destination: %w", err)
+		}
+	}
+
+	if opts.dryRun {
+		return true, nil
+	}
+	if err := os.Symlink(absSrc, dst); err != nil {
+		return false, fmt.Errorf("create symlink: %w", err)
+	}
+	return true, nil
+}
+
+// Recursively copy a skill directory, recreating its structure under dst.
+// Returns the number of files copied and skipped.
+func copySkill(name, src, dst string, opts skillsOptions) (int, int, error) {
+	copied, skipped := 0, 0
+	err := filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if opts.dryRun {
+				return nil
+			}
+			return os.MkdirAll(target, info.Mode().Perm())
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		display := filepath.Join(name, rel)
+
+		// Never clobber existing files unless forced
+		if dstInfo, err := os.Stat(target); err == nil {
+			if info.ModTime().Before(dstInfo.ModTime()) {
+				fmt.Fprintf(os.Stderr, "Warning: %s is older than the installed copy\n", display)
+			}
+			if !opts.force {
+				fmt.Printf("  skipped (exists): %s\n", display)
+				skipped++
+				return nil
+			}
+		}
+
+		if opts.dryRun {
+			fmt.Printf("  %s -> %s\n", path, target)
+			copied++
+			return nil
+		}
+
+		if err := copyFile(path, target); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not copy %s: %v\n", display, err)
+			return nil
+		}
+		copied++
+		return nil
+	})
+
+	return copied, skipped, err
+}
+
+// Report whether a command modifies the database. Unknown commands log a
+// thought, so they count as writes.
+func isWriteCommand(cmd string, args []string) bool {
+	switch cmd {
+	case "summarise", "summarize", "untagged", "search", "replay", "follow", "export", "trend",
+		"digest", "stats", "metrics", "count-per-day", "show", "history", "range", "find", "mood", "plan", "diff", "on-this-day", "attachments", "trash", "markers", "recent-markers", "cloud", "lint", "info", "init-skills":
+		return false
+	case "server", "repl":
+		// Long-running; holding the lock would block every other writer
+		return false
+	case "tmpl":
+		return len(args) == 0 || args[0] != "list"
+	}
+	return true
+}
+
 func printUsage() {
 	fmt.Fprintf(os.Stderr, `Usage:
-  prothought <thought text...>
-  prothought nvm
-  prothought summarise [today|yesterday|lastweek|lastmonth|YYYY-MM-DD] [#marker]
-  prothought summarize [today|yesterday|lastweek|lastmonth|YYYY-MM-DD] [#marker]
-  prothought init-skills
+  prothought [-v | -vv | --verbose] [--db PATH] [--lock] [--plain] [--relative] [--tz ZONE]
+             [--max-open-conns N] [--max-idle-conns N] <command>
+  prothought [--defer-markers] [--max-size SIZE] [--no-webhook] [--emoji] [--mood 1-5]
+             [--git] [--full] <thought text...>
+  prothought nvm [--confirm] [--yes]
+  prothought nvm <id>
+  prothought nvm #marker [--yes]
+  prothought summarise [today|yesterday|lastweek|lastmonth|ytd|thisyear|lastyear|YYYY-MM-DD|-N..M|last:N] [#marker]
+  prothought summarize [today|yesterday|lastweek|lastmonth|ytd|thisyear|lastyear|YYYY-MM-DD|-N..M|last:N] [#marker]
+             [--template TEXT | --template-file PATH] [--check-files]
+             [--min-words N] [--max-words N] [--only-ids] [--struck | --not-struck]
+             [--no-pager] [--count-by-marker] [--after HH:MM] [--before HH:MM]
+             [period...] [--discrete] [--markdown-table] [--since-id N]
+             [-#marker | --exclude #marker]... [--use-stored-markers] [--print-sql]
+  prothought untagged [period] [summarize flags...]
+  prothought tmpl save <name> <text> | use <name> | list
+  prothought find <query> [period] [--only-ids]
+  prothought search <text> [period] [#marker] [--only-markers] [--only-ids]
+             [--struck | --not-struck] [--in #marker] [--context N] [--print-sql]
+  prothought replay [period] [#marker] [--delay 3s]
+  prothought follow [#marker] [-n N] [--interval 1s]
+  prothought server [--addr 127.0.0.1:8080] [--write]
+  prothought repl
+  prothought rename-db <new-path> [--force]
+  prothought export [period] [#marker] [--redact #marker]...
+             [--format text|csv|tsv|json|ndjson|org] [--json] [--fields id,timestamp,text,markers,struck,mood]
+             [--since-last-export [--no-update]] [--with-meta] [--group-by-marker]
+  prothought log-file [--defer-markers] <file|->
+  prothought import [--format=prothought-json] [--lenient] [--defer-markers] <file|->
+  prothought trend #marker [period] [--weekly]
+  prothought count-per-day [period] [--format table|csv]
+  prothought stats [period] [--json] [--print-sql]
+  prothought metrics [--format prom] [--output FILE]
+  prothought diff <date1> <date2>
+  prothought on-this-day [YYYY-MM-DD]
+  prothought digest [week|today|yesterday|lastweek|lastmonth|YYYY-MM-DD] [--format md]
+  prothought init-skills [--force] [--link] [--dry-run] [--from DIR] [--to DIR]
+  prothought attachments <id>
+  prothought show <id> [--json | --md]
+  prothought history <id>
+  prothought range <start-id> <end-id> [#marker]
+  prothought mood [period]
+  prothought plan [period]
+  prothought done <id>
+  prothought catchup [period] [--min-gap 3h]
+  prothought edit <id> [new text...]
+  prothought edit-last [new text...]
+  prothought merge-thoughts <id1> <id2> [id...]
+  prothought edit-period [period] [#marker] [--yes]
+  prothought delete <id> | restore <id> | trash | empty-trash [--yes]
+  prothought purge-before <YYYY-MM-DD> [--dry-run] [--yes]
+  prothought markers [--last-seen]
+  prothought recent-markers [period] [--json]
+  prothought cloud [period] [--json]
+  prothought retag #old #new [--merge] [--yes]
+  prothought lint [--min-uses N] [--max-distance N]
+  prothought set-markers <id> [#marker...]
+  prothought tag-period [today|yesterday|lastweek|...|YYYY-MM-DD|last:N] #marker [--only #marker] [--yes]
+  prothought reindex-markers
+  prothought prune-markers [--orphans-only] [--dry-run] [--yes]
+  prothought info
+  prothought config get <key> | set <key> <value> | list
   prothought --version
 
 Examples:
@@ -399,6 +1631,9 @@ Examples:
   prothought summarize today #work
   prothought summarize lastweek #personal
   prothought init-skills
+  prothought untagged lastweek --only-ids
+  prothought tmpl save standup "Yesterday: \nToday: \nBlockers: #standup"
+  prothought tmpl use standup
 `)
 }
 
@@ -408,62 +1643,386 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Handle version flag
-	if os.Args[1] == "--version" || os.Args[1] == "-v" {
+	// Handle version flag; -v followed by a command means verbose
+	if os.Args[1] == "--version" || (os.Args[1] == "-v" && len(os.Args) == 2) {
 		fmt.Printf("prothought version %s (commit: %s, built: %s)\n", version, commit, date)
 		return
 	}
 
+	// Resolve configuration
+	globals, cmdArgs, err := parseGlobalFlags(os.Args[1:])
+	if err != nil {
+		fail("parsing arguments", err)
+	}
+	if len(cmdArgs) == 0 {
+		printUsage()
+		os.Exit(1)
+	}
+	cfg, err = loadConfig(globals)
+	if err != nil {
+		fail("loading config", err)
+	}
+	dbPath = expandHome(cfg.get("db_path"), homeDir)
+	debugf(1, "database %s (db_path from %s), config file %s", dbPath, cfg.sources["db_path"], configPath)
+
+	// Parse command
+	cmd := cmdArgs[0]
+	args := cmdArgs[1:]
+	currentCommand = cmd
+
+	// Commands that don't need the database
+	if cmd == "config" {
+		if err := runConfig(args); err != nil {
+			fail("", err)
+		}
+		return
+	}
+
+	// Serialize writers when asked to
+	if cfg.enabled("lock") && dbPath != ":memory:" && isWriteCommand(cmd, args) {
+		release, err := acquireLock(dbPath + ".lock")
+		if err != nil {
+			fail("acquiring lock", err)
+		}
+		defer release()
+	}
+
 	// Open database
 	db, err := sql.Open("sqlite3", dbPath)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
-		os.Exit(1)
+		fail("opening database", err)
 	}
 	defer db.Close()
+	// SQLite allows one writer at a time, so extra connections mostly add
+	// "database is locked" errors
+	maxOpen, _ := strconv.Atoi(cfg.get("max_open_conns"))
+	maxIdle, _ := strconv.Atoi(cfg.get("max_idle_conns"))
+	if dbPath == ":memory:" {
+		// Every connection would otherwise get its own empty database
+		maxOpen, maxIdle = 1, 1
+	}
+	db.SetMaxOpenConns(maxOpen)
+	db.SetMaxIdleConns(maxIdle)
 
 	// Initialize database
 	if err := initDB(db); err != nil {
-		fmt.Fprintf(os.Stderr, "Error initializing database: %v\n", err)
-		os.Exit(1)
+		fail("initializing database", err)
 	}
 
-	// Parse command
-	cmd := os.Args[1]
-	args := os.Args[2:]
+	runCommand(db, cmd, args)
+}
 
+// Run a command against an open database, exiting through fail on errors
+func runCommand(db *sql.DB, cmd string, args []string) {
 	switch cmd {
 	case "summarise", "summarize":
-		periodArgs, marker := parseArgsWithMarker(args)
-		if err := listThoughts(db, periodArgs, marker); err != nil {
-			fmt.Fprintf(os.Stderr, "Error listing thoughts: %v\n", err)
-			os.Exit(1)
+		opts, rest, err := parseListFlags(cmd, args)
+		if err != nil {
+			fail("parsing arguments", err)
+		}
+		periodArgs, marker := parseArgsWithMarker(rest)
+		var out bytes.Buffer
+		if err := listThoughts(db, &out, periodArgs, marker, opts); err != nil {
+			fail("listing thoughts", err)
+		}
+		if err := showPaged(out.Bytes(), opts.noPager); err != nil {
+			fail("showing output", err)
+		}
+
+	case "untagged":
+		opts, rest, err := parseListFlags(cmd, args)
+		if err != nil {
+			fail("parsing arguments", err)
+		}
+		periodArgs, marker := parseArgsWithMarker(rest)
+		if marker != "" {
+			fail("parsing arguments", fmt.Errorf("untagged thoughts have no markers to filter by"))
+		}
+		opts.untagged = true
+		var out bytes.Buffer
+		if err := listThoughts(db, &out, periodArgs, "", opts); err != nil {
+			fail("listing thoughts", err)
+		}
+		if err := showPaged(out.Bytes(), opts.noPager); err != nil {
+			fail("showing output", err)
 		}
 
 	case "nvm":
-		if err := strikeLastThought(db); err != nil {
-			fmt.Fprintf(os.Stderr, "Error striking thought: %v\n", err)
-			os.Exit(1)
+		opts, rest, err := parseStrikeFlags(cmd, args)
+		if err != nil {
+			fail("parsing arguments", err)
+		}
+		if len(rest) == 1 && strings.HasPrefix(rest[0], "#") {
+			err = strikeByMarker(db, strings.TrimPrefix(rest[0], "#"), opts)
+		} else if len(rest) == 1 {
+			err = strikeThoughtByID(db, rest[0])
+		} else if len(rest) > 0 {
+			err = fmt.Errorf("unexpected argument: %s", rest[0])
+		} else {
+			err = strikeLastThought(db, opts)
+		}
+		if err != nil {
+			fail("striking thought", err)
 		}
 
 	case "init-skills":
-		if err := initSkills(); err != nil {
-			fmt.Fprintf(os.Stderr, "Error initializing skills: %v\n", err)
-			os.Exit(1)
+		opts, err := parseSkillsFlags(cmd, args)
+		if err != nil {
+			fail("parsing arguments", err)
+		}
+		if err := initSkills(opts); err != nil {
+			fail("initializing skills", err)
+		}
+
+	case "trend":
+		if err := showTrend(db, cmd, args); err != nil {
+			fail("showing trend", err)
+		}
+
+	case "count-per-day":
+		if err := showCountPerDay(db, cmd, args); err != nil {
+			fail("counting thoughts", err)
+		}
+
+	case "metrics":
+		if err := exportMetrics(db, cmd, args); err != nil {
+			fail("exporting metrics", err)
+		}
+
+	case "stats":
+		if err := showStats(db, cmd, args); err != nil {
+			fail("showing stats", err)
+		}
+
+	case "diff":
+		if err := diffPeriods(db, args); err != nil {
+			fail("comparing periods", err)
+		}
+
+	case "on-this-day":
+		if err := showOnThisDay(db, args); err != nil {
+			fail("showing memories", err)
+		}
+
+	case "digest":
+		if err := showDigest(db, cmd, args); err != nil {
+			fail("building digest", err)
+		}
+
+	case "attachments":
+		if err := listAttachments(db, args); err != nil {
+			fail("listing attachments", err)
+		}
+
+	case "tmpl":
+		if err := runSnippets(db, args); err != nil {
+			fail("", err)
+		}
+
+	case "search":
+		if err := searchThoughts(db, cmd, args); err != nil {
+			fail("searching thoughts", err)
+		}
+
+	case "follow":
+		ctx, stop := signalContext()
+		err := followThoughts(ctx, db, cmd, args)
+		stop()
+		if err != nil {
+			fail("following thoughts", err)
+		}
+
+	case "server":
+		ctx, stop := signalContext()
+		err := runServer(ctx, db, cmd, args)
+		stop()
+		if err != nil {
+			fail("running server", err)
+		}
+
+	case "replay":
+		ctx, stop := signalContext()
+		err := replayThoughts(ctx, db, cmd, args)
+		stop()
+		if err != nil {
+			fail("replaying thoughts", err)
+		}
+
+	case "log-file":
+		if err := logFile(db, cmd, args); err != nil {
+			fail("logging thoughts", err)
+		}
+
+	case "import":
+		if err := importThoughts(db, cmd, args); err != nil {
+			fail("importing thoughts", err)
+		}
+
+	case "export":
+		if err := exportThoughts(db, os.Stdout, cmd, args); err != nil {
+			fail("exporting thoughts", err)
+		}
+
+	case "edit":
+		if err := editThoughtByID(db, args); err != nil {
+			fail("editing thought", err)
+		}
+
+	case "edit-last":
+		if err := editLastThought(db, args); err != nil {
+			fail("editing thought", err)
+		}
+
+	case "edit-period":
+		if err := editPeriod(db, cmd, args); err != nil {
+			fail("editing thoughts", err)
+		}
+
+	case "find":
+		if err := findThoughts(db, cmd, args); err != nil {
+			fail("finding thoughts", err)
+		}
+
+	case "range":
+		if err := showIDRange(db, args); err != nil {
+			fail("showing thoughts", err)
+		}
+
+	case "mood":
+		if err := showMood(db, args); err != nil {
+			fail("showing mood", err)
+		}
+
+	case "catchup":
+		if err := catchUp(db, cmd, args); err != nil {
+			fail("catching up", err)
+		}
+
+	case "plan":
+		if err := showPlan(db, args); err != nil {
+			fail("showing plans", err)
+		}
+
+	case "done":
+		if err := markDone(db, args); err != nil {
+			fail("closing plan", err)
+		}
+
+	case "history":
+		if err := showHistory(db, args); err != nil {
+			fail("showing history", err)
+		}
+
+	case "show":
+		if err := showThought(db, cmd, args); err != nil {
+			fail("showing thought", err)
+		}
+
+	case "delete":
+		if err := trashThought(db, args); err != nil {
+			fail("deleting thought", err)
+		}
+
+	case "restore":
+		if err := restoreThought(db, args); err != nil {
+			fail("restoring thought", err)
+		}
+
+	case "trash":
+		if err := listTrash(db); err != nil {
+			fail("listing trash", err)
+		}
+
+	case "empty-trash":
+		if err := emptyTrash(db, cmd, args); err != nil {
+			fail("emptying trash", err)
+		}
+
+	case "markers":
+		if err := listMarkers(db, cmd, args); err != nil {
+			fail("listing markers", err)
+		}
+
+	case "cloud":
+		if err := showCloud(db, cmd, args); err != nil {
+			fail("showing marker cloud", err)
+		}
+
+	case "recent-markers":
+		if err := showRecentMarkers(db, cmd, args); err != nil {
+			fail("listing markers", err)
+		}
+
+	case "purge-before":
+		if err := purgeBefore(db, cmd, args); err != nil {
+			fail("purging thoughts", err)
+		}
+
+	case "tag-period":
+		if err := tagPeriod(db, cmd, args); err != nil {
+			fail("tagging period", err)
+		}
+
+	case "set-markers":
+		if err := setMarkers(db, args); err != nil {
+			fail("setting markers", err)
+		}
+
+	case "lint":
+		if err := lintMarkers(db, cmd, args); err != nil {
+			fail("linting markers", err)
+		}
+
+	case "retag":
+		if err := retagMarkers(db, cmd, args); err != nil {
+			fail("retagging marker", err)
+		}
+
+	case "merge-thoughts":
+		if err := mergeThoughts(db, args); err != nil {
+			fail("merging thoughts", err)
+		}
+
+	case "prune-markers":
+		if err := pruneMarkers(db, cmd, args); err != nil {
+			fail("pruning markers", err)
+		}
+
+	case "reindex-markers":
+		if err := reindexMarkers(db); err != nil {
+			fail("reindexing markers", err)
+		}
+
+	case "rename-db":
+		if err := renameDB(db, cmd, args); err != nil {
+			fail("moving database", err)
+		}
+
+	case "info":
+		if err := showInfo(db); err != nil {
+			fail("showing info", err)
+		}
+
+	case "repl":
+		if err := runREPL(db); err != nil {
+			fail("running repl", err)
 		}
 
 	default:
-		// Log thought (everything as text)
-		thoughtText := strings.Join(os.Args[1:], " ")
+		// Log thought (everything after leading flags as text)
+		opts, words, err := parseAddFlags(append([]string{cmd}, args...))
+		if err != nil {
+			fail("parsing arguments", err)
+		}
+		thoughtText := strings.Join(words, " ")
 		thoughtText = strings.TrimSpace(thoughtText)
 		if thoughtText == "" {
 			printUsage()
-			os.Exit(1)
+			exit(1)
 		}
 
-		if err := logThought(db, thoughtText); err != nil {
-			fmt.Fprintf(os.Stderr, "Error logging thought: %v\n", err)
-			os.Exit(1)
+		if err := logThought(db, thoughtText, opts); err != nil {
+			fail("logging thought", err)
 		}
 	}
 }
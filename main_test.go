@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Load the default settings, ignoring the environment and any config file
+// of the user running the tests
+func newTestConfig(t *testing.T) {
+	t.Helper()
+	configPath = filepath.Join(t.TempDir(), "prothought.conf")
+	for _, s := range settings {
+		t.Setenv(s.env, "")
+	}
+	t.Setenv("NO_COLOR", "1")
+
+	var err error
+	if cfg, err = loadConfig(map[string]string{"plain": "true"}); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+}
+
+// Change a setting through its environment variable for the rest of the test
+func setTestSetting(t *testing.T, env, value string) {
+	t.Helper()
+	t.Setenv(env, value)
+	var err error
+	if cfg, err = loadConfig(map[string]string{"plain": "true"}); err != nil {
+		t.Fatalf("load config: %v", err)
+	}
+}
+
+// Open a fresh in-memory database with default settings
+func newTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+	newTestConfig(t)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	// Every connection would otherwise get its own empty database
+	db.SetMaxOpenConns(1)
+	if err := initDB(db); err != nil {
+		t.Fatalf("init db: %v", err)
+	}
+	return db
+}
+
+// Run fn and return what it printed to stdout
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		out, _ := io.ReadAll(r)
+		done <- string(out)
+	}()
+	fn()
+	w.Close()
+	return <-done
+}
+
+// Log a thought, failing the test on error
+func mustLog(t *testing.T, db *sql.DB, text string) string {
+	t.Helper()
+	var err error
+	out := captureStdout(t, func() { err = logThought(db, text, addOptions{}) })
+	if err != nil {
+		t.Fatalf("log %q: %v", text, err)
+	}
+	return out
+}
+
+// Load the markers of a thought as stored
+func storedMarkers(t *testing.T, db *sql.DB, id int64) []string {
+	t.Helper()
+	markers, err := markersForThoughts(db, []Thought{{ID: id}})
+	if err != nil {
+		t.Fatalf("load markers: %v", err)
+	}
+	return markers[id]
+}
+
+func TestLogListStrikeSummarize(t *testing.T) {
+	db := newTestDB(t)
+
+	out := mustLog(t, db, "Fixed the login redirect #work #bugfix")
+	if !strings.Contains(out, "with markers: #work, #bugfix") {
+		t.Errorf("log confirmation = %q, want the markers listed", out)
+	}
+	mustLog(t, db, "Bought groceries #personal")
+
+	thoughts, err := queryThoughts(db, thoughtQuery{})
+	if err != nil {
+		t.Fatalf("query thoughts: %v", err)
+	}
+	if len(thoughts) != 2 {
+		t.Fatalf("got %d thoughts, want 2", len(thoughts))
+	}
+	if got := strings.Join(storedMarkers(t, db, thoughts[0].ID), ","); got != "work,bugfix" {
+		t.Errorf("markers of first thought = %q, want work,bugfix", got)
+	}
+
+	work, err := queryThoughts(db, thoughtQuery{marker: "work"})
+	if err != nil {
+		t.Fatalf("query by marker: %v", err)
+	}
+	if len(work) != 1 || work[0].ID != thoughts[0].ID {
+		t.Errorf("thoughts with #work = %v, want only thought %d", work, thoughts[0].ID)
+	}
+
+	var buf bytes.Buffer
+	if err := listThoughts(db, &buf, []string{"today"}, "", listOptions{}); err != nil {
+		t.Fatalf("list thoughts: %v", err)
+	}
+	for _, text := range []string{"Fixed the login redirect", "Bought groceries"} {
+		if !strings.Contains(buf.String(), text) {
+			t.Errorf("list output %q is missing %q", buf.String(), text)
+		}
+	}
+
+	// nvm with no arguments strikes the most recent thought
+	out = captureStdout(t, func() { runCommand(db, "nvm", nil) })
+	if !strings.Contains(out, "Marked last thought") {
+		t.Errorf("nvm output = %q", out)
+	}
+	var text string
+	if err := db.QueryRow("SELECT text FROM thoughts WHERE id = ?", thoughts[1].ID).Scan(&text); err != nil {
+		t.Fatalf("query struck thought: %v", err)
+	}
+	if text != strikeText("Bought groceries #personal") || !isStruck(text) {
+		t.Errorf("struck text = %q", text)
+	}
+
+	out = captureStdout(t, func() { runCommand(db, "summarize", []string{"today", "--not-struck", "--no-pager"}) })
+	if !strings.Contains(out, "Fixed the login redirect") || strings.Contains(out, "Bought groceries") {
+		t.Errorf("summarize --not-struck = %q, want only the unstruck thought", out)
+	}
+	out = captureStdout(t, func() { runCommand(db, "summarize", []string{"today", "#personal", "--no-pager"}) })
+	if !strings.Contains(out, "Bought groceries") || strings.Contains(out, "login") {
+		t.Errorf("summarize #personal = %q, want only the #personal thought", out)
+	}
+}
+
+func TestParsePeriodYearBoundaries(t *testing.T) {
+	newTestConfig(t)
+	newYear := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.Local)
+	newYearsEve := time.Date(2026, time.December, 31, 23, 59, 0, 0, time.Local)
+
+	tests := []struct {
+		name       string
+		now        time.Time
+		period     string
+		start, end string
+	}{
+		{"ytd on Jan 1", newYear, "ytd", "2026-01-01T00:00:00", "2026-01-01T23:59:59"},
+		{"thisyear on Jan 1", newYear, "thisyear", "2026-01-01T00:00:00", "2026-12-31T23:59:59"},
+		{"lastyear on Jan 1", newYear, "lastyear", "2025-01-01T00:00:00", "2025-12-31T23:59:59"},
+		{"yesterday on Jan 1", newYear, "yesterday", "2025-12-31T00:00:00", "2025-12-31T23:59:59"},
+		{"ytd on Dec 31", newYearsEve, "ytd", "2026-01-01T00:00:00", "2026-12-31T23:59:59"},
+		{"thisyear on Dec 31", newYearsEve, "this_year", "2026-01-01T00:00:00", "2026-12-31T23:59:59"},
+		{"lastyear on Dec 31", newYearsEve, "last_year", "2025-01-01T00:00:00", "2025-12-31T23:59:59"},
+		{"today on Dec 31", newYearsEve, "today", "2026-12-31T00:00:00", "2026-12-31T23:59:59"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := parsePeriodAt([]string{tt.period}, tt.now)
+			if err != nil {
+				t.Fatalf("parse %s: %v", tt.period, err)
+			}
+			if start != tt.start || end != tt.end {
+				t.Errorf("%s = %s .. %s, want %s .. %s", tt.period, start, end, tt.start, tt.end)
+			}
+		})
+	}
+}
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// markerNameRegex matches a valid marker name without the leading #
+var markerNameRegex = regexp.MustCompile(`^[\p{L}\p{M}\p{N}_-]+$`)
+
+// Normalize a marker given as #key[=value] or key[=value] the way log
+// extracts it from text, reporting false for anything log wouldn't extract
+func parseLoggableMarker(tag string) (string, bool) {
+	tag = "#" + strings.TrimPrefix(tag, "#")
+	if hashtagRegex.FindString(tag) != tag {
+		return "", false
+	}
+	return extractHashtags(tag)[0], true
+}
+
+// Remove accents so that, for example, café becomes cafe
+func stripDiacritics(s string) string {
+	t := transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	result, _, err := transform.String(t, s)
+	if err != nil {
+		return s
+	}
+	return result
+}
+
+// Add the configured default marker to a new thought's markers unless it
+// already carries it
+func withDefaultMarker(hashtags []string) []string {
+	def := cfg.get("default_marker")
+	if def == "" {
+		return hashtags
+	}
+	def = extractHashtags("#" + strings.TrimPrefix(def, "#"))[0]
+	for _, tag := range hashtags {
+		if tag == def {
+			return hashtags
+		}
+	}
+	return append(hashtags, def)
+}
+
+// Rewrite the hashtags in a thought's text to match its stored markers,
+// which can differ after retag, set-markers or tag-period. Hashtags
+// without a stored marker are replaced, in order, by stored markers
+// missing from the text, or dropped; any stored markers left over are
+// appended.
+func useStoredMarkers(text string, stored []string) string {
+	has := make(map[string]bool)
+	for _, tag := range stored {
+		has[tag] = true
+	}
+	inText := make(map[string]bool)
+	for _, tag := range extractHashtags(text) {
+		inText[tag] = true
+	}
+	var missing []string
+	for _, tag := range stored {
+		if !inText[tag] {
+			missing = append(missing, tag)
+		}
+	}
+
+	text = hashtagRegex.ReplaceAllStringFunc(text, func(match string) string {
+		if has[extractHashtags(match)[0]] {
+			return match
+		}
+		if len(missing) == 0 {
+			return ""
+		}
+		tag := "#" + missing[0]
+		missing = missing[1:]
+		return tag
+	})
+	text = strings.TrimSpace(doubleSpaceRegex.ReplaceAllString(text, " "))
+	for _, tag := range missing {
+		text += " #" + tag
+	}
+	return text
+}
+
+// doubleSpaceRegex matches the gap left behind by a dropped hashtag
+var doubleSpaceRegex = regexp.MustCompile(`[ \t]{2,}`)
+
+// Load the stored markers of a thought within a transaction
+func thoughtMarkersTx(tx *sql.Tx, id int64) ([]string, error) {
+	rows, err := tx.Query("SELECT marker, value FROM markers WHERE thought_id = ? ORDER BY id", id)
+	if err != nil {
+		return nil, fmt.Errorf("query markers: %w", err)
+	}
+	defer rows.Close()
+	var tags []string
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("scan marker: %w", err)
+		}
+		if value != "" {
+			key += "=" + value
+		}
+		tags = append(tags, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query markers: %w", err)
+	}
+	return tags, nil
+}
+
+// Pick out the stored markers that aren't among a text's hashtags, such as
+// #done, the default marker and those added by --git, tag-period or
+// set-markers, normalized with the current settings. Re-extracting a
+// thought's hashtags keeps these.
+func markersNotInText(stored, hashtags []string) []string {
+	has := make(map[string]bool)
+	for _, tag := range hashtags {
+		has[tag] = true
+	}
+	var kept []string
+	for _, tag := range stored {
+		key, value := splitMarker(tag)
+		tag = normalizeMarker(key)
+		if value != "" {
+			tag += "=" + normalizeMarker(value)
+		}
+		if !has[tag] {
+			has[tag] = true
+			kept = append(kept, tag)
+		}
+	}
+	return kept
+}
+
+// Rebuild the markers table by re-extracting hashtags from every thought.
+// Markers that were never in the text are kept; prune-markers removes those.
+func reindexMarkers(db *sql.DB) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query("SELECT id, text FROM thoughts ORDER BY id ASC")
+	if err != nil {
+		return fmt.Errorf("query thoughts: %w", err)
+	}
+	var thoughts []Thought
+	for rows.Next() {
+		var t Thought
+		if err := rows.Scan(&t.ID, &t.Text); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan thought: %w", err)
+		}
+		thoughts = append(thoughts, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query thoughts: %w", err)
+	}
+
+	kept := make(map[int64][]string)
+	for _, t := range thoughts {
+		stored, err := thoughtMarkersTx(tx, t.ID)
+		if err != nil {
+			return err
+		}
+		kept[t.ID] = markersNotInText(stored, extractHashtags(t.Text))
+	}
+
+	if _, err := tx.Exec("DELETE FROM markers"); err != nil {
+		return fmt.Errorf("clear markers: %w", err)
+	}
+
+	markerCount, keptCount := 0, 0
+	for _, t := range thoughts {
+		tags := append(extractHashtags(t.Text), kept[t.ID]...)
+		if err := insertMarkers(tx, t.ID, tags); err != nil {
+			return err
+		}
+		markerCount += len(tags)
+		keptCount += len(kept[t.ID])
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	keptInfo := ""
+	if keptCount > 0 {
+		keptInfo = fmt.Sprintf(", keeping %d not in the text", keptCount)
+	}
+	fmt.Printf("Reindexed %d marker(s) across %d thought(s)%s.\n", markerCount, len(thoughts), keptInfo)
+	return nil
+}
+
+// Rename a marker across every thought. When the new marker is already in
+// use --merge is required, and thoughts carrying both keep a single row.
+func retagMarkers(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	merge := fs.Bool("merge", false, "merge into a marker that already exists")
+	yes := fs.Bool("yes", false, "never ask for confirmation")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 2 || !strings.HasPrefix(rest[0], "#") || !strings.HasPrefix(rest[1], "#") {
+		return fmt.Errorf("usage: prothought retag #old #new [--merge] [--yes]")
+	}
+
+	newTag := strings.TrimPrefix(rest[1], "#")
+	if !markerNameRegex.MatchString(newTag) {
+		return fmt.Errorf("invalid marker: %s", rest[1])
+	}
+	from, to := normalizeMarker(strings.TrimPrefix(rest[0], "#")), normalizeMarker(newTag)
+	if from == to {
+		return fmt.Errorf("#%s and #%s are the same marker", from, to)
+	}
+
+	// Count and ask before opening the transaction so no lock is held
+	// while waiting for an answer
+	var existing int
+	if err := db.QueryRow("SELECT COUNT(*) FROM markers WHERE marker = ?", to).Scan(&existing); err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+	if existing > 0 && !*merge {
+		return fmt.Errorf("#%s is already used by %d thought(s); pass --merge to combine them", to, existing)
+	}
+
+	rows, err := db.Query(`
+		SELECT DISTINCT t.id, t.text
+		FROM thoughts t
+		INNER JOIN markers m ON t.id = m.thought_id
+		WHERE m.marker = ?`, from)
+	if err != nil {
+		return fmt.Errorf("query thoughts: %w", err)
+	}
+	var thoughts []Thought
+	for rows.Next() {
+		var t Thought
+		if err := rows.Scan(&t.ID, &t.Text); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan thought: %w", err)
+		}
+		thoughts = append(thoughts, t)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query thoughts: %w", err)
+	}
+	if len(thoughts) == 0 {
+		return fmt.Errorf("no thoughts with marker #%s", from)
+	}
+	ok, err := confirmBulk(len(thoughts), fmt.Sprintf("retag %d thought(s) from #%s to #%s", len(thoughts), from, to), *yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Rewrite the hashtag in the text so a later reindex agrees
+	for _, t := range thoughts {
+		text := hashtagRegex.ReplaceAllStringFunc(t.Text, func(tag string) string {
+			key, value, hasValue := strings.Cut(tag[1:], "=")
+			if normalizeMarker(key) != from {
+				return tag
+			}
+			if hasValue {
+				return "#" + newTag + "=" + value
+			}
+			return "#" + newTag
+		})
+		if _, err := tx.Exec("UPDATE thoughts SET text = ? WHERE id = ?", text, t.ID); err != nil {
+			return fmt.Errorf("update thought: %w", err)
+		}
+	}
+
+	// Drop the old row where a thought already carries the new marker
+	result, err := tx.Exec(`
+		DELETE FROM markers
+		WHERE marker = ?
+		  AND thought_id IN (SELECT thought_id FROM markers WHERE marker = ?)`, from, to)
+	if err != nil {
+		return fmt.Errorf("delete duplicate markers: %w", err)
+	}
+	overlap, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("count duplicate markers: %w", err)
+	}
+	if _, err := tx.Exec("UPDATE markers SET marker = ? WHERE marker = ?", to, from); err != nil {
+		return fmt.Errorf("update markers: %w", err)
+	}
+
+	var total int
+	if err := tx.QueryRow("SELECT COUNT(DISTINCT thought_id) FROM markers WHERE marker = ?", to).Scan(&total); err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Printf("Retagged %d thought(s) from #%s to #%s (%d already had both); #%s is now on %d thought(s).\n",
+		len(thoughts), from, to, overlap, to, total)
+	return nil
+}
+
+// Add a marker to every thought in a period without touching the text,
+// optionally only to those already carrying another marker
+func tagPeriod(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	only := fs.String("only", "", "only tag thoughts carrying this marker")
+	yes := fs.Bool("yes", false, "never ask for confirmation")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 || len(rest) > 2 || !strings.HasPrefix(rest[len(rest)-1], "#") {
+		return fmt.Errorf("usage: prothought tag-period [period] #marker [--only #marker] [--yes]")
+	}
+
+	tag := strings.TrimPrefix(rest[len(rest)-1], "#")
+	if !markerNameRegex.MatchString(tag) {
+		return fmt.Errorf("invalid marker: %s", rest[len(rest)-1])
+	}
+	tag = normalizeMarker(tag)
+
+	q, err := periodQuery(rest[:len(rest)-1])
+	if err != nil {
+		return err
+	}
+	q.marker = strings.TrimPrefix(*only, "#")
+
+	query, queryArgs := q.build()
+	selection := `FROM (` + query + `) WHERE id NOT IN (SELECT thought_id FROM markers WHERE marker = ?)`
+	selectionArgs := append(queryArgs, tag)
+
+	var pending int
+	if err := db.QueryRow("SELECT COUNT(*) "+selection, selectionArgs...).Scan(&pending); err != nil {
+		return fmt.Errorf("count thoughts: %w", err)
+	}
+	// Ask before opening the transaction so no lock is held meanwhile
+	ok, err := confirmBulk(pending, fmt.Sprintf("tag %d thought(s) with #%s", pending, tag), *yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO markers (thought_id, marker) SELECT id, ? "+selection,
+		append([]interface{}{tag}, selectionArgs...)...)
+	if err != nil {
+		return fmt.Errorf("insert markers: %w", err)
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("count inserted markers: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Printf("Tagged %d thought(s) with #%s.\n", added, tag)
+	return nil
+}
+
+// Replace the markers of a thought with exactly the given ones, leaving
+// its text as it is
+func setMarkers(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: prothought set-markers <id> [#marker...]")
+	}
+	id, err := parseThoughtID(args[0])
+	if err != nil {
+		return err
+	}
+
+	var tags []string
+	seen := make(map[string]bool)
+	for _, arg := range args[1:] {
+		key, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "#"), "=")
+		if !strings.HasPrefix(arg, "#") || !markerNameRegex.MatchString(key) || (hasValue && !markerNameRegex.MatchString(value)) {
+			return fmt.Errorf("invalid marker: %s", arg)
+		}
+		tag := normalizeMarker(key)
+		if hasValue {
+			tag += "=" + normalizeMarker(value)
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	if err := tx.QueryRow("SELECT EXISTS(SELECT 1 FROM thoughts WHERE id = ? AND deleted_at IS NULL)", id).Scan(&exists); err != nil {
+		return fmt.Errorf("query thought: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("no thought with id %d", id)
+	}
+
+	if _, err := tx.Exec("DELETE FROM markers WHERE thought_id = ?", id); err != nil {
+		return fmt.Errorf("delete markers: %w", err)
+	}
+	if err := insertMarkers(tx, id, tags); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	if len(tags) == 0 {
+		fmt.Printf("Thought %d now has no markers.\n", id)
+		return nil
+	}
+	fmt.Printf("Thought %d now has markers: %s\n", id, joinMarkers(tags))
+	return nil
+}
+
+// Remove markers whose hashtag the thought's text doesn't contain, along
+// with rows whose thought is gone. That includes markers added on purpose
+// without a hashtag, which --orphans-only leaves alone. Unlike
+// reindex-markers nothing is added, and with --dry-run nothing is removed
+// either.
+func pruneMarkers(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "list the stale markers without removing them")
+	yes := fs.Bool("yes", false, "never ask for confirmation")
+	orphansOnly := fs.Bool("orphans-only", false, "only remove markers whose thought no longer exists")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument: %s", rest[0])
+	}
+
+	rows, err := db.Query(`
+		SELECT m.id, m.thought_id, m.marker, m.value, t.text
+		FROM markers m
+		LEFT JOIN thoughts t ON t.id = m.thought_id
+		ORDER BY m.thought_id, m.id`)
+	if err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+	type staleMarker struct {
+		id, thoughtID int64
+		tag           string
+		orphan        bool
+	}
+	var stale []staleMarker
+	inText := make(map[int64]map[string]bool)
+	for rows.Next() {
+		var s staleMarker
+		var key, value string
+		var text sql.NullString
+		if err := rows.Scan(&s.id, &s.thoughtID, &key, &value, &text); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan marker: %w", err)
+		}
+		s.tag = key
+		if value != "" {
+			s.tag += "=" + value
+		}
+		if !text.Valid {
+			s.orphan = true
+			stale = append(stale, s)
+			continue
+		}
+		if *orphansOnly {
+			continue
+		}
+		if inText[s.thoughtID] == nil {
+			inText[s.thoughtID] = make(map[string]bool)
+			for _, tag := range extractHashtags(text.String) {
+				inText[s.thoughtID][tag] = true
+			}
+		}
+		if !inText[s.thoughtID][s.tag] {
+			stale = append(stale, s)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+
+	if len(stale) == 0 {
+		fmt.Println("No stale markers.")
+		return nil
+	}
+
+	thoughts := make(map[int64]bool)
+	notInTextCount := 0
+	for _, s := range stale {
+		thoughts[s.thoughtID] = true
+		note := " (thought no longer exists)"
+		if !s.orphan {
+			note = " (not in the text)"
+			notInTextCount++
+		}
+		fmt.Printf("  thought %-6d #%s%s\n", s.thoughtID, s.tag, note)
+	}
+	if *dryRun {
+		fmt.Printf("Would remove %d stale marker(s) from %d thought(s).\n", len(stale), len(thoughts))
+		return nil
+	}
+
+	action := fmt.Sprintf("remove %d marker(s) from %d thought(s)", len(stale), len(thoughts))
+	if notInTextCount > 0 {
+		fmt.Printf("%d of these markers are not in the text; they may have been added on purpose by done, tag-period, set-markers, default_marker or --git, which --orphans-only keeps.\n", notInTextCount)
+		action = fmt.Sprintf("remove %d marker(s), %d of them not in the text, from %d thought(s)", len(stale), notInTextCount, len(thoughts))
+	}
+	ok, err := confirmBulk(len(thoughts), action, *yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for _, s := range stale {
+		if _, err := tx.Exec("DELETE FROM markers WHERE id = ?", s.id); err != nil {
+			return fmt.Errorf("delete marker: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Printf("Removed %d stale marker(s) from %d thought(s).\n", len(stale), len(thoughts))
+	return nil
+}
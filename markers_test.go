@@ -0,0 +1,98 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRetagMergeOverlap(t *testing.T) {
+	db := newTestDB(t)
+	mustLog(t, db, "Both markers #a #b")
+	mustLog(t, db, "Only the old one #a")
+	mustLog(t, db, "Only the new one #b")
+
+	if err := retagMarkers(db, "retag", []string{"#a", "#b", "--yes"}); err == nil {
+		t.Fatal("retag into a marker in use succeeded without --merge")
+	}
+
+	var err error
+	out := captureStdout(t, func() { err = retagMarkers(db, "retag", []string{"#a", "#b", "--merge", "--yes"}) })
+	if err != nil {
+		t.Fatalf("retag --merge: %v", err)
+	}
+	want := "Retagged 2 thought(s) from #a to #b (1 already had both); #b is now on 3 thought(s).\n"
+	if out != want {
+		t.Errorf("retag output = %q, want %q", out, want)
+	}
+
+	var rows, old int
+	if err := db.QueryRow("SELECT COUNT(*) FROM markers WHERE thought_id = 1 AND marker = 'b'").Scan(&rows); err != nil {
+		t.Fatalf("count markers: %v", err)
+	}
+	if rows != 1 {
+		t.Errorf("thought 1 has %d #b row(s), want 1", rows)
+	}
+	if err := db.QueryRow("SELECT COUNT(*) FROM markers WHERE marker = 'a'").Scan(&old); err != nil {
+		t.Fatalf("count markers: %v", err)
+	}
+	if old != 0 {
+		t.Errorf("%d #a row(s) left after the merge, want 0", old)
+	}
+
+	var text string
+	if err := db.QueryRow("SELECT text FROM thoughts WHERE id = 2").Scan(&text); err != nil {
+		t.Fatalf("query thought: %v", err)
+	}
+	if strings.Contains(text, "#a") || !strings.Contains(text, "#b") {
+		t.Errorf("text of thought 2 = %q, want the hashtag rewritten to #b", text)
+	}
+}
+
+func TestPruneMarkers(t *testing.T) {
+	db := newTestDB(t)
+	mustLog(t, db, "Kept as is #a")
+	captureStdout(t, func() {
+		if err := tagPeriod(db, "tag-period", []string{"today", "#added", "--yes"}); err != nil {
+			t.Fatalf("tag-period: %v", err)
+		}
+	})
+	if _, err := db.Exec("INSERT INTO markers (thought_id, marker, value) VALUES (99, 'gone', '')"); err != nil {
+		t.Fatalf("insert orphan marker: %v", err)
+	}
+	markerCount := func() int {
+		t.Helper()
+		var n int
+		if err := db.QueryRow("SELECT COUNT(*) FROM markers").Scan(&n); err != nil {
+			t.Fatalf("count markers: %v", err)
+		}
+		return n
+	}
+
+	var err error
+	out := captureStdout(t, func() { err = pruneMarkers(db, "prune-markers", []string{"--dry-run"}) })
+	if err != nil {
+		t.Fatalf("prune --dry-run: %v", err)
+	}
+	if !strings.Contains(out, "#added (not in the text)") || !strings.Contains(out, "#gone (thought no longer exists)") {
+		t.Errorf("prune --dry-run output = %q, want both stale markers listed", out)
+	}
+	if n := markerCount(); n != 3 {
+		t.Errorf("%d marker(s) after --dry-run, want all 3", n)
+	}
+
+	captureStdout(t, func() { err = pruneMarkers(db, "prune-markers", []string{"--orphans-only", "--yes"}) })
+	if err != nil {
+		t.Fatalf("prune --orphans-only: %v", err)
+	}
+	if got := strings.Join(storedMarkers(t, db, 1), ","); got != "a,added" || markerCount() != 2 {
+		t.Errorf("markers after --orphans-only = %q, want a,added and the orphan gone", got)
+	}
+
+	captureStdout(t, func() { err = pruneMarkers(db, "prune-markers", []string{"--yes"}) })
+	if err != nil {
+		t.Fatalf("prune: %v", err)
+	}
+	if got := strings.Join(storedMarkers(t, db, 1), ","); got != "a" || markerCount() != 1 {
+		t.Errorf("markers after prune = %q, want only a", got)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Combine several thoughts into a new one with the earliest timestamp,
+// their texts joined by newlines in the order they were logged, the union
+// of their markers and their mood, then delete the originals. Their edit
+// history moves to the new thought.
+func mergeThoughts(db *sql.DB, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: prothought merge-thoughts <id1> <id2> [id...]")
+	}
+	seen := make(map[int64]bool)
+	var ids []interface{}
+	for _, arg := range args {
+		id, err := parseThoughtID(arg)
+		if err != nil {
+			return err
+		}
+		if seen[id] {
+			return fmt.Errorf("thought %d is given more than once", id)
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	rows, err := db.Query(`
+		SELECT id, timestamp, text, mood
+		FROM thoughts
+		WHERE deleted_at IS NULL AND id IN (`+placeholders+`)
+		ORDER BY timestamp ASC, id ASC`, ids...)
+	if err != nil {
+		return fmt.Errorf("query thoughts: %w", err)
+	}
+	var thoughts []Thought
+	var mood sql.NullInt64
+	var moodFrom int64
+	for rows.Next() {
+		var t Thought
+		var m sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.Timestamp, &t.Text, &m); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan thought: %w", err)
+		}
+		if m.Valid && mood.Valid && m.Int64 != mood.Int64 {
+			rows.Close()
+			return fmt.Errorf("thoughts %d and %d have different moods (%d and %d); merge thoughts with the same mood", moodFrom, t.ID, mood.Int64, m.Int64)
+		}
+		if m.Valid && !mood.Valid {
+			mood, moodFrom = m, t.ID
+		}
+		thoughts = append(thoughts, t)
+		delete(seen, t.ID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query thoughts: %w", err)
+	}
+	for _, arg := range args {
+		if id, _ := parseThoughtID(arg); seen[id] {
+			return fmt.Errorf("thought %d does not exist or is in the trash", id)
+		}
+	}
+
+	// Union of the stored markers, which may include ones not in the text
+	markers, err := markersForThoughts(db, thoughts)
+	if err != nil {
+		return err
+	}
+	var tags []string
+	has := make(map[string]bool)
+	texts := make([]string, len(thoughts))
+	for i, t := range thoughts {
+		texts[i] = t.Text
+		for _, tag := range markers[t.ID] {
+			if !has[tag] {
+				has[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	merged := Thought{Timestamp: thoughts[0].Timestamp, Text: strings.Join(texts, "\n")}
+
+	ok, err := confirmProtected(db, "merge", "id IN ("+placeholders+")", ids...)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("INSERT INTO thoughts (timestamp, text, mood) VALUES (?, ?, ?)", merged.Timestamp, merged.Text, mood)
+	if err != nil {
+		return fmt.Errorf("insert thought: %w", err)
+	}
+	if merged.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("get last insert id: %w", err)
+	}
+	if err := insertMarkers(tx, merged.ID, tags); err != nil {
+		return err
+	}
+	if err := insertAttachments(tx, merged.ID, extractAttachments(merged.Text)); err != nil {
+		return err
+	}
+	if _, err := tx.Exec("UPDATE thought_history SET thought_id = ? WHERE thought_id IN ("+placeholders+")",
+		append([]interface{}{merged.ID}, ids...)...); err != nil {
+		return fmt.Errorf("move history: %w", err)
+	}
+	if _, err := deleteThoughtsWhere(tx, "id IN ("+placeholders+")", ids...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	markerInfo := ""
+	if len(tags) > 0 {
+		markerInfo = " with markers: " + joinMarkers(tags)
+	}
+	if mood.Valid {
+		markerInfo += fmt.Sprintf(" (mood %d)", mood.Int64)
+	}
+	fmt.Printf("Merged %d thoughts into thought %d%s\n", len(thoughts), merged.ID, markerInfo)
+	fmt.Println(formatThought(merged))
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+func TestMergeKeepsDoneMoodAndHistory(t *testing.T) {
+	db := newTestDB(t)
+	for _, text := range []string{"Write the report #plan", "Send it out #plan"} {
+		var err error
+		captureStdout(t, func() { err = logThought(db, text, addOptions{mood: 4}) })
+		if err != nil {
+			t.Fatalf("log %q: %v", text, err)
+		}
+	}
+	captureStdout(t, func() {
+		if err := markDone(db, []string{"1"}); err != nil {
+			t.Fatalf("done: %v", err)
+		}
+		if _, err := updateThought(db, 2, "Send it to the team #plan"); err != nil {
+			t.Fatalf("edit: %v", err)
+		}
+	})
+
+	var err error
+	out := captureStdout(t, func() { err = mergeThoughts(db, []string{"1", "2"}) })
+	if err != nil {
+		t.Fatalf("merge: %v", err)
+	}
+	if !strings.Contains(out, "into thought 3") {
+		t.Fatalf("merge output = %q, want the new thought 3", out)
+	}
+
+	if got := strings.Join(storedMarkers(t, db, 3), ","); got != "plan,done" {
+		t.Errorf("markers of merged thought = %q, want plan,done", got)
+	}
+	var mood sql.NullInt64
+	if err := db.QueryRow("SELECT mood FROM thoughts WHERE id = 3").Scan(&mood); err != nil {
+		t.Fatalf("query mood: %v", err)
+	}
+	if !mood.Valid || mood.Int64 != 4 {
+		t.Errorf("mood of merged thought = %v, want 4", mood)
+	}
+	var history int
+	if err := db.QueryRow("SELECT COUNT(*) FROM thought_history WHERE thought_id = 3").Scan(&history); err != nil {
+		t.Fatalf("count history: %v", err)
+	}
+	if history != 1 {
+		t.Errorf("merged thought has %d history row(s), want the 1 edit of thought 2", history)
+	}
+
+	plans, err := openPlans(db, nil)
+	if err != nil {
+		t.Fatalf("open plans: %v", err)
+	}
+	if len(plans) != 0 {
+		t.Errorf("got %d open plan(s) after merging a done one, want 0", len(plans))
+	}
+}
+
+func TestMergeRefusesConflictingMoods(t *testing.T) {
+	db := newTestDB(t)
+	for i, text := range []string{"Good morning", "Bad afternoon"} {
+		var err error
+		captureStdout(t, func() { err = logThought(db, text, addOptions{mood: 5 - 3*i}) })
+		if err != nil {
+			t.Fatalf("log %q: %v", text, err)
+		}
+	}
+
+	err := mergeThoughts(db, []string{"1", "2"})
+	if err == nil || !strings.Contains(err.Error(), "different moods") {
+		t.Fatalf("merge of thoughts with moods 5 and 2 = %v, want a mood conflict", err)
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM thoughts").Scan(&n); err != nil {
+		t.Fatalf("count thoughts: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("%d thought(s) after the refused merge, want 2", n)
+	}
+}
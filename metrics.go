@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Count the thoughts selected by a query
+func countThoughts(db *sql.DB, q thoughtQuery) (int, error) {
+	query, args := q.build()
+	query = "SELECT COUNT(*) FROM (" + query + ")"
+	debugf(1, "query: %s", oneLineSQL(query))
+	debugf(1, "args: %v", args)
+	start := time.Now()
+	var n int
+	if err := db.QueryRow(query, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count thoughts: %w", err)
+	}
+	debugf(2, "query took %s", time.Since(start))
+	return n, nil
+}
+
+// Escape a Prometheus label value
+var promLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// Write journal metrics in the Prometheus text format, to stdout or
+// atomically to a file for the node_exporter textfile collector
+func exportMetrics(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "prom", "output format: prom")
+	output := fs.String("output", "", "write to this file instead of stdout")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument: %s", rest[0])
+	}
+	if *format != "prom" {
+		return fmt.Errorf("unsupported metrics format: %s", *format)
+	}
+
+	total, err := countThoughts(db, thoughtQuery{})
+	if err != nil {
+		return err
+	}
+	struck, err := countThoughts(db, thoughtQuery{struck: onlyStruck})
+	if err != nil {
+		return err
+	}
+	trashed, err := countThoughts(db, thoughtQuery{trash: true})
+	if err != nil {
+		return err
+	}
+	markers, err := countMarkersForQuery(db, thoughtQuery{})
+	if err != nil {
+		return err
+	}
+	var last sql.NullString
+	if err := db.QueryRow("SELECT MAX(timestamp) FROM thoughts WHERE deleted_at IS NULL").Scan(&last); err != nil {
+		return fmt.Errorf("query last thought: %w", err)
+	}
+
+	var buf bytes.Buffer
+	gauge := func(name, help string) {
+		fmt.Fprintf(&buf, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+	gauge("prothought_total", "Number of thoughts, excluding the trash.")
+	fmt.Fprintf(&buf, "prothought_total %d\n", total)
+	gauge("prothought_struck_total", "Number of thoughts struck through with nvm.")
+	fmt.Fprintf(&buf, "prothought_struck_total %d\n", struck)
+	gauge("prothought_trash_total", "Number of thoughts in the trash.")
+	fmt.Fprintf(&buf, "prothought_trash_total %d\n", trashed)
+	gauge("prothought_markers_total", "Number of distinct markers in use.")
+	fmt.Fprintf(&buf, "prothought_markers_total %d\n", len(markers))
+	gauge("prothought_marker_thoughts", "Number of thoughts carrying each marker.")
+	for _, mc := range markers {
+		fmt.Fprintf(&buf, "prothought_marker_thoughts{marker=\"%s\"} %d\n", promLabelReplacer.Replace(mc.Marker), mc.Count)
+	}
+	if last.Valid {
+		if t, err := time.ParseInLocation(timestampFormat, last.String, time.Local); err == nil {
+			gauge("prothought_last_thought_timestamp_seconds", "Unix time of the most recent thought.")
+			fmt.Fprintf(&buf, "prothought_last_thought_timestamp_seconds %d\n", t.Unix())
+		}
+	}
+
+	if *output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return writeFileAtomic(*output, buf.Bytes())
+}
+
+// Write a file by renaming a temporary file into place, so readers never
+// see it half written
+func writeFileAtomic(path string, data []byte) error {
+	// Same directory so the rename stays on one filesystem
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write temporary file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("chmod temporary file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temporary file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("rename temporary file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Moods are scored on this scale, from 1 to maxMood
+const maxMood = 5
+
+// Parse a mood score given with --mood
+func parseMood(v string) (int, error) {
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > maxMood {
+		return 0, fmt.Errorf("mood must be a whole number from 1 to %d, got %q", maxMood, v)
+	}
+	return n, nil
+}
+
+// Look up the mood of each thought that has one
+func moodsForThoughts(db *sql.DB, thoughts []Thought) (map[int64]int, error) {
+	moods := make(map[int64]int)
+	if len(thoughts) == 0 {
+		return moods, nil
+	}
+	ids := make([]interface{}, len(thoughts))
+	for i, t := range thoughts {
+		ids[i] = t.ID
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")
+
+	rows, err := db.Query("SELECT id, mood FROM thoughts WHERE mood IS NOT NULL AND id IN ("+placeholders+")", ids...)
+	if err != nil {
+		return nil, fmt.Errorf("query moods: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var mood int
+		if err := rows.Scan(&id, &mood); err != nil {
+			return nil, fmt.Errorf("scan mood: %w", err)
+		}
+		moods[id] = mood
+	}
+	return moods, rows.Err()
+}
+
+// Print the average mood of each day in a period that has any
+func showMood(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		args = []string{"lastweek"}
+	}
+	startTS, endTS, err := parsePeriod(args)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT substr(timestamp, 1, 10) AS day, AVG(mood), COUNT(mood)
+		FROM thoughts
+		WHERE mood IS NOT NULL
+		  AND substr(timestamp, 1, 19) BETWEEN ? AND ?
+		  AND deleted_at IS NULL
+		GROUP BY day
+		ORDER BY day`, startTS, endTS)
+	if err != nil {
+		return fmt.Errorf("query moods: %w", err)
+	}
+	defer rows.Close()
+
+	days, total, sum := 0, 0, 0.0
+	for rows.Next() {
+		var day string
+		var avg float64
+		var n int
+		if err := rows.Scan(&day, &avg, &n); err != nil {
+			return fmt.Errorf("scan mood: %w", err)
+		}
+		if days == 0 {
+			fmt.Printf("%-10s  %4s  %-*s  %s\n", "DAY", "MOOD", maxMood, "", "THOUGHTS")
+		}
+		bar := strings.Repeat("█", int(math.Round(avg)))
+		fmt.Printf("%-10s  %4.1f  %-*s  %d\n", day, avg, maxMood, bar, n)
+		days++
+		total += n
+		sum += avg * float64(n)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query moods: %w", err)
+	}
+
+	if days == 0 {
+		fmt.Println("No moods logged for that period.")
+		return nil
+	}
+	fmt.Printf("\nAverage %.1f over %d thought(s) on %d day(s).\n", sum/float64(total), total, days)
+	return nil
+}
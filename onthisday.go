@@ -0,0 +1,69 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Show thoughts logged on the same month and day in previous years
+func showOnThisDay(db *sql.DB, args []string) error {
+	day := time.Now()
+	if len(args) > 1 {
+		return fmt.Errorf("usage: prothought on-this-day [YYYY-MM-DD]")
+	}
+	if len(args) == 1 {
+		parsed, err := time.ParseInLocation("2006-01-02", args[0], time.Local)
+		if err != nil {
+			return fmt.Errorf("invalid date: %s", args[0])
+		}
+		day = parsed
+	}
+
+	rows, err := db.Query(`
+		SELECT id, timestamp, text
+		FROM thoughts
+		WHERE strftime('%m-%d', timestamp) = ?
+		  AND strftime('%Y', timestamp) < ?
+		  AND deleted_at IS NULL
+		ORDER BY strftime('%Y', timestamp) DESC, timestamp ASC, id ASC`,
+		day.Format("01-02"), day.Format("2006"))
+	if err != nil {
+		return fmt.Errorf("query thoughts: %w", err)
+	}
+	defer rows.Close()
+
+	// Newest year first, each year's thoughts in the order they were logged
+	var years []string
+	byYear := make(map[string][]Thought)
+	for rows.Next() {
+		var t Thought
+		if err := rows.Scan(&t.ID, &t.Timestamp, &t.Text); err != nil {
+			return fmt.Errorf("scan thought: %w", err)
+		}
+		year := t.Timestamp[:4]
+		if _, ok := byYear[year]; !ok {
+			years = append(years, year)
+		}
+		byYear[year] = append(byYear[year], t)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query thoughts: %w", err)
+	}
+
+	if len(years) == 0 {
+		fmt.Printf("No thoughts from %s in previous years.\n", day.Format("January 2"))
+		return nil
+	}
+
+	for i, year := range years {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(colorize(colorCyan, fmt.Sprintf("%s (%d)", year, len(byYear[year]))))
+		for _, t := range byYear[year] {
+			fmt.Printf("  %s\n", formatThought(t))
+		}
+	}
+	return nil
+}
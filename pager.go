@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Write output to stdout, piping it through $PAGER when stdout is a
+// terminal and the output would not fit on one screen
+func showPaged(output []byte, noPager bool) error {
+	if noPager || cfg.enabled("plain") || !isTerminal(os.Stdout) || fitsOnScreen(output) {
+		_, err := os.Stdout.Write(output)
+		return err
+	}
+
+	// The pager command may carry its own arguments, e.g. "less -R"
+	pager := firstNonEmpty(os.Getenv("PAGER"), "less -R")
+	parts := strings.Fields(pager)
+	cmd := exec.Command(parts[0], parts[1:]...)
+	cmd.Stdin = bytes.NewReader(output)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		// No usable pager; print directly rather than lose the output
+		fmt.Fprintf(os.Stderr, "Warning: could not start pager %s: %v\n", pager, err)
+		_, err := os.Stdout.Write(output)
+		return err
+	}
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("run pager %s: %w", pager, err)
+	}
+	return nil
+}
+
+// Report whether output fits within the terminal height
+func fitsOnScreen(output []byte) bool {
+	_, height, err := term.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height <= 0 {
+		return true
+	}
+	return bytes.Count(output, []byte("\n")) < height
+}
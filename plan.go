@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Reserved markers for planning: #plan opens an item and #done closes it
+const (
+	planMarker = "plan"
+	doneMarker = "done"
+)
+
+// Find the #plan thoughts in a period that aren't marked #done yet
+func openPlans(db *sql.DB, periodArgs []string) ([]Thought, error) {
+	if len(periodArgs) == 0 {
+		periodArgs = []string{"today"}
+	}
+	q, err := periodQuery(periodArgs)
+	if err != nil {
+		return nil, err
+	}
+	q.marker = planMarker
+	q.exclude = []string{doneMarker}
+	return queryThoughts(db, q)
+}
+
+// Print the planned items of a period, today by default, that are still open
+func showPlan(db *sql.DB, args []string) error {
+	plans, err := openPlans(db, args)
+	if err != nil {
+		return err
+	}
+	if len(plans) == 0 {
+		fmt.Println("No open plans.")
+		return nil
+	}
+	for _, t := range plans {
+		fmt.Printf("%d  %s\n", t.ID, formatThought(t))
+	}
+	fmt.Printf("\n%d open plan(s). Close one with: prothought done <id>\n", len(plans))
+	return nil
+}
+
+// Close a planned item by adding the #done marker, then report what is left
+// for today. Like tag-period the marker goes in the index only, and edits
+// and reindex-markers keep it.
+func markDone(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: prothought done <id>")
+	}
+	id, err := parseThoughtID(args[0])
+	if err != nil {
+		return err
+	}
+
+	var deletedAt sql.NullString
+	err = db.QueryRow("SELECT deleted_at FROM thoughts WHERE id = ?", id).Scan(&deletedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no thought with id %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("query thought: %w", err)
+	}
+	if deletedAt.Valid {
+		return fmt.Errorf("thought %d is in the trash; bring it back with: prothought restore %d", id, id)
+	}
+
+	var planned, done int
+	if err := db.QueryRow(`
+		SELECT COUNT(CASE WHEN marker = ? THEN 1 END), COUNT(CASE WHEN marker = ? THEN 1 END)
+		FROM markers
+		WHERE thought_id = ? AND value = ''`, planMarker, doneMarker, id).Scan(&planned, &done); err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+	if planned == 0 {
+		return fmt.Errorf("thought %d is not marked #%s", id, planMarker)
+	}
+	if done > 0 {
+		fmt.Printf("Thought %d is already marked #%s.\n", id, doneMarker)
+	} else {
+		if err := insertMarkers(db, id, []string{doneMarker}); err != nil {
+			return err
+		}
+		fmt.Printf("Marked thought %d as #%s.\n", id, doneMarker)
+	}
+
+	plans, err := openPlans(db, nil)
+	if err != nil {
+		return err
+	}
+	if len(plans) == 0 {
+		fmt.Println("No open plans left for today.")
+	} else {
+		fmt.Printf("%d open plan(s) left for today. See them with: prothought plan\n", len(plans))
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanAndDone(t *testing.T) {
+	db := newTestDB(t)
+	mustLog(t, db, "Write the quarterly report #plan")
+	mustLog(t, db, "Call the bank #plan")
+	mustLog(t, db, "Lunch with the team")
+	mustLog(t, db, "Renew the domain #plan #done")
+
+	if err := markDone(db, []string{"3"}); err == nil || !strings.Contains(err.Error(), "not marked #plan") {
+		t.Errorf("done on a thought without #plan = %v, want it refused", err)
+	}
+
+	var err error
+	out := captureStdout(t, func() { err = markDone(db, []string{"1"}) })
+	if err != nil {
+		t.Fatalf("done: %v", err)
+	}
+	if !strings.Contains(out, "Marked thought 1 as #done.") || !strings.Contains(out, "1 open plan(s) left") {
+		t.Errorf("done output = %q", out)
+	}
+	out = captureStdout(t, func() { err = markDone(db, []string{"1"}) })
+	if err != nil || !strings.Contains(out, "already marked #done") {
+		t.Errorf("done twice = %q, %v", out, err)
+	}
+
+	// Closing survives editing and reindexing
+	if _, err := updateThought(db, 1, "Write the yearly report #plan"); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	captureStdout(t, func() { err = reindexMarkers(db) })
+	if err != nil {
+		t.Fatalf("reindex: %v", err)
+	}
+
+	plans, err := openPlans(db, nil)
+	if err != nil {
+		t.Fatalf("open plans: %v", err)
+	}
+	if len(plans) != 1 || plans[0].ID != 2 {
+		t.Errorf("open plans = %v, want only thought 2", plans)
+	}
+
+	done, err := queryThoughts(db, thoughtQuery{marker: doneMarker})
+	if err != nil {
+		t.Fatalf("query #done: %v", err)
+	}
+	if len(done) != 2 || done[0].ID != 1 || done[1].ID != 4 {
+		t.Errorf("thoughts with #done = %v, want thoughts 1 and 4", done)
+	}
+}
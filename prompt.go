@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Report whether f is an interactive terminal
+func isTerminal(f *os.File) bool {
+	return term.IsTerminal(int(f.Fd()))
+}
+
+// Ask a yes/no question on stdin, defaulting to no
+func confirm(prompt string) (bool, error) {
+	fmt.Printf("%s [y/N] ", prompt)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		// EOF without an answer counts as no
+		fmt.Println()
+		return false, nil
+	}
+
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// Report whether a bulk change to n thoughts needs confirming: it affects
+// more than confirm_threshold thoughts and --yes was not given
+func needsConfirm(n int, yes bool) bool {
+	threshold, _ := strconv.Atoi(cfg.get("confirm_threshold"))
+	return !yes && n > threshold
+}
+
+// Ask before a bulk change to n thoughts described by action, such as
+// "strike 143 thought(s)". Without a terminal to ask on, --yes is required.
+func confirmBulk(n int, action string, yes bool) (bool, error) {
+	if !needsConfirm(n, yes) {
+		return true, nil
+	}
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("refusing to %s without confirmation; pass --yes", action)
+	}
+	return confirm(fmt.Sprintf("This will %s. Continue?", action))
+}
+
+// Parse the comma-separated protected_markers setting
+func protectedMarkers() []string {
+	var markers []string
+	for _, m := range strings.Split(cfg.get("protected_markers"), ",") {
+		if m = strings.TrimSpace(m); m != "" {
+			markers = append(markers, normalizeMarkerArg(m))
+		}
+	}
+	return markers
+}
+
+// Ask before striking or deleting thoughts that carry a protected marker.
+// The thoughts are those matching cond on the thoughts table. This is
+// asked even when --yes or confirm_threshold would skip other prompts,
+// and without a terminal the change is refused.
+func confirmProtected(db *sql.DB, action string, cond string, args ...interface{}) (bool, error) {
+	protected := protectedMarkers()
+	if len(protected) == 0 {
+		return true, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(protected)), ",")
+	queryArgs := make([]interface{}, 0, len(protected)+len(args))
+	for _, m := range protected {
+		queryArgs = append(queryArgs, m)
+	}
+	queryArgs = append(queryArgs, args...)
+
+	var n int
+	var found sql.NullString
+	if err := db.QueryRow(`
+		SELECT COUNT(DISTINCT thought_id), GROUP_CONCAT(DISTINCT marker)
+		FROM markers
+		WHERE marker IN (`+placeholders+`)
+		  AND thought_id IN (SELECT id FROM thoughts WHERE `+cond+`)`, queryArgs...).Scan(&n, &found); err != nil {
+		return false, fmt.Errorf("check protected markers: %w", err)
+	}
+	if n == 0 {
+		return true, nil
+	}
+
+	what := fmt.Sprintf("%d thought(s) with protected markers (%s)", n, joinMarkers(strings.Split(found.String, ",")))
+	if !isTerminal(os.Stdin) {
+		return false, fmt.Errorf("refusing to %s %s without a terminal to confirm on", action, what)
+	}
+	return confirm(fmt.Sprintf("This will %s %s. Continue?", action, what))
+}
+
+// Build a condition matching thoughts by id
+func idsCondition(ids []int64) (string, []interface{}) {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return "id IN (" + strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",") + ")", args
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Permanently delete every thought logged before a date, for retention
+func purgeBefore(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	dryRun := fs.Bool("dry-run", false, "show what would be deleted without deleting")
+	yes := fs.Bool("yes", false, "never ask for confirmation")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: prothought purge-before <YYYY-MM-DD> [--dry-run] [--yes]")
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", rest[0], time.Local)
+	if err != nil {
+		return fmt.Errorf("invalid date: %s", rest[0])
+	}
+	if date.After(time.Now()) {
+		return fmt.Errorf("refusing to purge before a future date: %s", rest[0])
+	}
+	cutoff := date.Format(timestampFormat)
+
+	var count int
+	var first, last sql.NullString
+	if err := db.QueryRow(`
+		SELECT COUNT(*), MIN(timestamp), MAX(timestamp)
+		FROM thoughts
+		WHERE timestamp < ?`, cutoff).Scan(&count, &first, &last); err != nil {
+		return fmt.Errorf("query thoughts: %w", err)
+	}
+	if count == 0 {
+		fmt.Printf("No thoughts before %s.\n", rest[0])
+		return nil
+	}
+	dateRange := fmt.Sprintf("%s .. %s", first.String, last.String)
+
+	if *dryRun {
+		fmt.Printf("Would permanently delete %d thought(s) from %s\n", count, dateRange)
+		return nil
+	}
+
+	ok, err := confirmBulk(count, fmt.Sprintf("permanently delete %d thought(s) from %s", count, dateRange), *yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+	ok, err = confirmProtected(db, "permanently delete", "timestamp < ?", cutoff)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	deleted, err := deleteThoughtsWhere(tx, "timestamp < ?", cutoff)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Printf("Permanently deleted %d thought(s) from %s\n", deleted, dateRange)
+	return nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// struckFilter selects thoughts by whether they are struck through
+type struckFilter int
+
+const (
+	anyStruck struckFilter = iota
+	onlyStruck
+	notStruck
+)
+
+// Resolve the mutually exclusive --struck and --not-struck flags
+func parseStruckFilter(struck, kept bool) (struckFilter, error) {
+	switch {
+	case struck && kept:
+		return anyStruck, fmt.Errorf("--struck and --not-struck cannot be used together")
+	case struck:
+		return onlyStruck, nil
+	case kept:
+		return notStruck, nil
+	}
+	return anyStruck, nil
+}
+
+// Build the SQL equivalent of isStruck for the configured strike tokens
+func struckCondition() (string, []interface{}) {
+	prefix, suffix := strikeTokens()
+	var conds []string
+	var args []interface{}
+	if prefix != "" {
+		conds = append(conds, "substr(t.text, 1, ?) = ?")
+		args = append(args, utf8.RuneCountInString(prefix), prefix)
+	}
+	if suffix != "" {
+		conds = append(conds, "substr(t.text, -?) = ?")
+		args = append(args, utf8.RuneCountInString(suffix), suffix)
+	}
+	return "(" + strings.Join(conds, " AND ") + ")", args
+}
+
+// thoughtQuery describes a selection of thoughts. Every filter is optional.
+type thoughtQuery struct {
+	start  string // inclusive timestamp bounds
+	end    string
+	also   [][2]string // further start and end bounds, any of which may match
+	marker string      // only thoughts carrying this marker
+	text   string      // only thoughts whose text contains this
+	limit  int         // only the most recent N matching thoughts
+	after  int64       // only thoughts with a greater id
+	upTo   int64       // only thoughts with at most this id
+	trash  bool        // select trashed thoughts instead of live ones
+	struck struckFilter
+
+	untagged bool     // only thoughts without any marker
+	exclude  []string // only thoughts carrying none of these markers
+}
+
+// Build the SQL and bound arguments for the query
+func (q thoughtQuery) build() (string, []interface{}) {
+	var joins, where []string
+	var args []interface{}
+
+	if q.trash {
+		where = append(where, "t.deleted_at IS NOT NULL")
+	} else {
+		where = append(where, "t.deleted_at IS NULL")
+	}
+
+	if q.marker != "" {
+		key, value := splitMarker(q.marker)
+		joins = append(joins, "INNER JOIN markers m ON t.id = m.thought_id")
+		where = append(where, "m.marker = ?")
+		args = append(args, normalizeMarker(key))
+		if value != "" {
+			where = append(where, "m.value = ?")
+			args = append(args, normalizeMarker(value))
+		}
+	}
+	for _, m := range q.exclude {
+		key, value := splitMarker(m)
+		sub := "SELECT thought_id FROM markers WHERE marker = ?"
+		args = append(args, normalizeMarker(key))
+		if value != "" {
+			sub += " AND value = ?"
+			args = append(args, normalizeMarker(value))
+		}
+		where = append(where, "t.id NOT IN ("+sub+")")
+	}
+	if q.untagged {
+		joins = append(joins, "LEFT JOIN markers um ON t.id = um.thought_id")
+		where = append(where, "um.id IS NULL")
+	}
+	if q.after > 0 {
+		where = append(where, "t.id > ?")
+		args = append(args, q.after)
+	}
+	if q.upTo > 0 {
+		where = append(where, "t.id <= ?")
+		args = append(args, q.upTo)
+	}
+	if cond, condArgs := q.rangeCondition("t.timestamp"); cond != "" {
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+	if q.struck != anyStruck {
+		cond, condArgs := struckCondition()
+		if q.struck == notStruck {
+			cond = "NOT " + cond
+		}
+		where = append(where, cond)
+		args = append(args, condArgs...)
+	}
+	if q.text != "" {
+		where = append(where, `t.text LIKE ? ESCAPE '\'`)
+		args = append(args, "%"+escapeLike(q.text)+"%")
+	}
+
+	query := "SELECT DISTINCT t.id, t.timestamp, t.text\nFROM thoughts t"
+	for _, j := range joins {
+		query += "\n" + j
+	}
+	query += "\nWHERE " + strings.Join(where, "\n  AND ")
+	if q.limit > 0 {
+		// Take the newest rows, then present them oldest first
+		query += "\nORDER BY t.timestamp DESC, t.id DESC\nLIMIT ?"
+		args = append(args, q.limit)
+		return "SELECT * FROM (\n" + query + "\n)\nORDER BY timestamp ASC, id ASC", args
+	}
+	query += "\nORDER BY t.timestamp ASC, t.id ASC"
+
+	return query, args
+}
+
+// Build the SQL restricting column to the query's time ranges, or nothing
+// when the query has none
+func (q thoughtQuery) rangeCondition(column string) (string, []interface{}) {
+	if q.start == "" || q.end == "" {
+		return "", nil
+	}
+	// Bounds are whole seconds; ignore any fraction so the end is inclusive
+	column = "substr(" + column + ", 1, 19)"
+	conds := []string{column + " BETWEEN ? AND ?"}
+	args := []interface{}{q.start, q.end}
+	for _, r := range q.also {
+		conds = append(conds, column+" BETWEEN ? AND ?")
+		args = append(args, r[0], r[1])
+	}
+	if len(conds) == 1 {
+		return conds[0], args
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+// Escape LIKE wildcards so the text matches literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+// Run a thought query
+func queryThoughts(db *sql.DB, q thoughtQuery) ([]Thought, error) {
+	query, args := q.build()
+	debugf(1, "query: %s", oneLineSQL(query))
+	debugf(1, "args: %v", args)
+	start := time.Now()
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query thoughts: %w", err)
+	}
+	defer rows.Close()
+
+	var thoughts []Thought
+	for rows.Next() {
+		var t Thought
+		if err := rows.Scan(&t.ID, &t.Timestamp, &t.Text); err != nil {
+			return nil, fmt.Errorf("scan thought: %w", err)
+		}
+		thoughts = append(thoughts, t)
+	}
+	debugf(1, "%d row(s) scanned", len(thoughts))
+	debugf(2, "query took %s", time.Since(start))
+
+	return thoughts, rows.Err()
+}
+
+// Print SQLite's plan for a thought query instead of running it
+func explainQuery(db *sql.DB, q thoughtQuery) error {
+	query, args := q.build()
+	rows, err := db.Query("EXPLAIN QUERY PLAN "+query, args...)
+	if err != nil {
+		return fmt.Errorf("explain query: %w", err)
+	}
+	defer rows.Close()
+
+	fmt.Println(query)
+	fmt.Println()
+	fmt.Println("QUERY PLAN")
+
+	// Indent each step under its parent
+	depth := make(map[int]int)
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			return fmt.Errorf("scan query plan: %w", err)
+		}
+		depth[id] = depth[parent] + 1
+		fmt.Printf("%s%s\n", strings.Repeat("  ", depth[id]-1), detail)
+	}
+
+	return rows.Err()
+}
+
+// Print a statement and its bound parameters to stderr instead of running
+// it, as a script that can be pasted into the sqlite3 shell
+func printSQL(query string, args []interface{}) {
+	fmt.Fprintf(os.Stderr, "%s;\n", strings.TrimSpace(query))
+	for i, arg := range args {
+		fmt.Fprintf(os.Stderr, "-- ?%d = %s\n", i+1, sqlLiteral(arg))
+	}
+}
+
+// Render a bound parameter as an SQL literal
+func sqlLiteral(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Count how many of the query's thoughts carry each marker, most used first
+func countMarkersForQuery(db *sql.DB, q thoughtQuery) ([]markerCount, error) {
+	query, args := q.build()
+	rows, err := db.Query(`
+		SELECT m.marker, COUNT(DISTINCT m.thought_id) AS n
+		FROM markers m
+		WHERE m.thought_id IN (SELECT id FROM (`+query+`))
+		GROUP BY m.marker
+		ORDER BY n DESC, m.marker ASC`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("count markers: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []markerCount
+	for rows.Next() {
+		var mc markerCount
+		if err := rows.Scan(&mc.Marker, &mc.Count); err != nil {
+			return nil, fmt.Errorf("scan marker count: %w", err)
+		}
+		counts = append(counts, mc)
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+)
+
+// Print the thoughts with ids from start to end inclusive, in id order,
+// optionally only those carrying a marker
+func showIDRange(db *sql.DB, args []string) error {
+	rest, marker := parseArgsWithMarker(args)
+	if len(rest) != 2 {
+		return fmt.Errorf("usage: prothought range <start-id> <end-id> [#marker]")
+	}
+	start, err := parseThoughtID(rest[0])
+	if err != nil {
+		return err
+	}
+	end, err := parseThoughtID(rest[1])
+	if err != nil {
+		return err
+	}
+	if start > end {
+		return fmt.Errorf("start id %d is greater than end id %d", start, end)
+	}
+
+	thoughts, err := queryThoughts(db, thoughtQuery{after: start - 1, upTo: end, marker: marker})
+	if err != nil {
+		return err
+	}
+	sort.Slice(thoughts, func(i, j int) bool { return thoughts[i].ID < thoughts[j].ID })
+
+	if len(thoughts) == 0 {
+		if marker != "" {
+			fmt.Printf("No thoughts with ids %d to %d and marker #%s.\n", start, end, marker)
+		} else {
+			fmt.Printf("No thoughts with ids %d to %d.\n", start, end)
+		}
+		return nil
+	}
+	for _, t := range thoughts {
+		fmt.Printf("%d  %s\n", t.ID, formatThought(t))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// recentMarker is a marker with the time it was last used
+type recentMarker struct {
+	Marker   string `json:"marker"`
+	LastUsed string `json:"last_used"`
+}
+
+// List markers used in a period, most recently used first, for editor
+// completion
+func showRecentMarkers(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "print a JSON array")
+	periodArgs, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(periodArgs) == 0 {
+		periodArgs = []string{"lastweek"}
+	}
+	startTS, endTS, err := parsePeriod(periodArgs)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT m.marker, MAX(t.timestamp) AS last_used
+		FROM markers m
+		INNER JOIN thoughts t ON t.id = m.thought_id
+		WHERE substr(t.timestamp, 1, 19) BETWEEN ? AND ?
+		  AND t.deleted_at IS NULL
+		GROUP BY m.marker
+		ORDER BY last_used DESC, m.marker ASC`, startTS, endTS)
+	if err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+	defer rows.Close()
+
+	markers := []recentMarker{}
+	for rows.Next() {
+		var rm recentMarker
+		if err := rows.Scan(&rm.Marker, &rm.LastUsed); err != nil {
+			return fmt.Errorf("scan marker: %w", err)
+		}
+		markers = append(markers, rm)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(markers); err != nil {
+			return fmt.Errorf("encode json: %w", err)
+		}
+		return nil
+	}
+
+	for _, rm := range markers {
+		fmt.Printf("#%-20s %s\n", rm.Marker, displayTime(rm.LastUsed))
+	}
+	return nil
+}
+
+// List every marker in use with how many thoughts carry it, most used
+// first, or with lastSeen the date each was last used, most recent first
+func listMarkers(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	lastSeen := fs.Bool("last-seen", false, "show when each marker was last used, most recent first")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument: %s", rest[0])
+	}
+
+	order := "n DESC, m.marker ASC"
+	if *lastSeen {
+		order = "last_seen DESC, m.marker ASC"
+	}
+	rows, err := db.Query(`
+		SELECT m.marker, COUNT(DISTINCT m.thought_id) AS n, MAX(t.timestamp) AS last_seen
+		FROM markers m
+		INNER JOIN thoughts t ON t.id = m.thought_id
+		WHERE t.deleted_at IS NULL
+		GROUP BY m.marker
+		ORDER BY ` + order)
+	if err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var marker, last string
+		var n int
+		if err := rows.Scan(&marker, &n, &last); err != nil {
+			return fmt.Errorf("scan marker: %w", err)
+		}
+		if *lastSeen {
+			fmt.Printf("#%-20s %5d  %s\n", marker, n, last[:10])
+		} else {
+			fmt.Printf("#%-20s %5d\n", marker, n)
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+
+	if count == 0 {
+		fmt.Println("No markers in use.")
+	}
+	return nil
+}
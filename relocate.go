@@ -0,0 +1,79 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Move the database to a new path with VACUUM INTO, point db_path in the
+// config file at it and remove the old file
+func renameDB(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	force := fs.Bool("force", false, "replace an existing file at the new path")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: prothought rename-db <new-path> [--force]")
+	}
+	if dbPath == ":memory:" {
+		return fmt.Errorf("an in-memory database has no file to move")
+	}
+
+	target, err := filepath.Abs(expandHome(rest[0], homeDir))
+	if err != nil {
+		return fmt.Errorf("resolve new path: %w", err)
+	}
+	current, err := filepath.Abs(dbPath)
+	if err != nil {
+		return fmt.Errorf("resolve database path: %w", err)
+	}
+	if target == current {
+		return fmt.Errorf("the database is already at %s", target)
+	}
+
+	if _, err := os.Stat(target); err == nil {
+		if !*force {
+			return fmt.Errorf("%s already exists; pass --force to replace it", target)
+		}
+		// VACUUM INTO refuses to write over an existing database
+		if err := os.Remove(target); err != nil {
+			return fmt.Errorf("remove existing file: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("check new path: %w", err)
+	}
+
+	if _, err := db.Exec("VACUUM INTO ?", target); err != nil {
+		return fmt.Errorf("copy database: %w", err)
+	}
+	if err := writeConfigValue(configPath, "db_path", target); err != nil {
+		return err
+	}
+
+	// Only remove the original once the copy and config are in place
+	if err := db.Close(); err != nil {
+		return fmt.Errorf("close database: %w", err)
+	}
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(current + suffix); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("remove old database: %w", err)
+		}
+	}
+
+	fmt.Printf("Moved database to %s and set db_path in %s\n", target, configPath)
+	// The environment and flags take precedence over the config file
+	switch cfg.sources["db_path"] {
+	case "env":
+		fmt.Fprintln(os.Stderr, "Warning: PROTHOUGHT_DB is set and still points at the old path; update it too")
+	case "flag":
+		fmt.Fprintln(os.Stderr, "Warning: --db was given; use the new path from now on")
+	}
+	return nil
+}
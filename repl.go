@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Read commands from stdin and run each against the already open database
+// until EOF or quit. A failing command reports its error and the session
+// carries on.
+func runREPL(db *sql.DB) error {
+	interactive := isTerminal(os.Stdin)
+	reader := bufio.NewReader(os.Stdin)
+
+	inREPL = true
+	defer func() { inREPL = false }()
+
+	for {
+		if interactive {
+			fmt.Print("prothought> ")
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			if interactive {
+				fmt.Println()
+			}
+			return nil
+		}
+
+		words, splitErr := splitCommandLine(line)
+		if splitErr != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", splitErr)
+			continue
+		}
+		if len(words) == 0 {
+			continue
+		}
+		if words[0] == "quit" || words[0] == "exit" {
+			return nil
+		}
+		runREPLLine(db, words[0], words[1:])
+	}
+}
+
+// Run one REPL command, recovering from fail so the session continues
+func runREPLLine(db *sql.DB, cmd string, args []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(replAbort); !ok {
+				panic(r)
+			}
+		}
+	}()
+
+	currentCommand = cmd
+	switch cmd {
+	case "repl":
+		fail("", fmt.Errorf("already in the REPL"))
+	case "config":
+		if err := runConfig(args); err != nil {
+			fail("", err)
+		}
+		return
+	}
+
+	// Lock per command rather than for the whole session
+	if cfg.enabled("lock") && dbPath != ":memory:" && isWriteCommand(cmd, args) {
+		release, err := acquireLock(dbPath + ".lock")
+		if err != nil {
+			fail("acquiring lock", err)
+		}
+		defer release()
+	}
+
+	runCommand(db, cmd, args)
+}
+
+// Split a line into words like a shell would, honouring single and double
+// quotes so multi-word arguments can be given
+func splitCommandLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+
+	for _, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
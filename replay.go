@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+const clearScreen = "\033[H\033[2J"
+
+// Print a period's thoughts one at a time, pausing between them, until
+// done or ctx is cancelled
+func replayThoughts(ctx context.Context, db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	delay := fs.Duration("delay", 3*time.Second, "pause between thoughts")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *delay < 0 {
+		return fmt.Errorf("--delay cannot be negative")
+	}
+
+	periodArgs, marker := parseArgsWithMarker(rest)
+	thoughts, err := thoughtsForPeriod(db, periodArgs, marker)
+	if err != nil {
+		return err
+	}
+	if len(thoughts) == 0 {
+		fmt.Println("No thoughts found for that period.")
+		return nil
+	}
+
+	// Only clear the screen on a terminal so piped output stays readable
+	clear := isTerminal(os.Stdout) && !cfg.enabled("plain")
+	for i, t := range thoughts {
+		if i > 0 && !sleepContext(ctx, *delay) {
+			return nil
+		}
+		if clear {
+			fmt.Print(clearScreen)
+		}
+		fmt.Printf("%s  (%d/%d)\n\n%s\n\n", colorize(colorDim, displayTime(t.Timestamp)), i+1, len(thoughts), highlightHashtags(t.Text))
+	}
+
+	return nil
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Search thoughts for text, optionally narrowed by marker and period.
+// Without a period every thought is searched.
+func searchThoughts(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	onlyMarkers := fs.Bool("only-markers", false, "print the markers of matching thoughts with counts")
+	onlyIDs := fs.Bool("only-ids", false, "print only the ids of matching thoughts")
+	explain := fs.Bool("explain", false, "print the query plan instead of results")
+	showSQL := fs.Bool("print-sql", false, "print the SQL and its parameters to stderr instead of running it")
+	struck := fs.Bool("struck", false, "only thoughts that are struck through")
+	kept := fs.Bool("not-struck", false, "only thoughts that are not struck through")
+	in := fs.String("in", "", "only search thoughts carrying this marker, printing ids and snippets")
+	context := fs.Int("context", 0, "also show this many thoughts before and after each match")
+	args, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	filter, err := parseStruckFilter(*struck, *kept)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: prothought search <text> [period] [#marker]")
+	}
+	if *context < 0 {
+		return fmt.Errorf("--context cannot be negative")
+	}
+	if *context > 0 && (*onlyIDs || *onlyMarkers || *in != "") {
+		return fmt.Errorf("--context cannot be combined with --only-ids, --only-markers or --in")
+	}
+
+	var q thoughtQuery
+	periodArgs, marker := parseArgsWithMarker(args[1:])
+	if len(periodArgs) > 0 {
+		if q, err = periodQuery(periodArgs); err != nil {
+			return err
+		}
+	}
+	q.text = args[0]
+	q.marker = marker
+	q.struck = filter
+	if *in != "" {
+		if marker != "" {
+			return fmt.Errorf("use either --in or a #marker argument, not both")
+		}
+		q.marker = strings.TrimPrefix(*in, "#")
+		if err := warnUnknownMarker(db, q.marker); err != nil {
+			return err
+		}
+	}
+	if *explain {
+		return explainQuery(db, q)
+	}
+	if *showSQL {
+		printSQL(q.build())
+		return nil
+	}
+
+	thoughts, err := queryThoughts(db, q)
+	if err != nil {
+		return err
+	}
+	if *onlyIDs {
+		printIDs(os.Stdout, thoughts)
+		return nil
+	}
+
+	if len(thoughts) == 0 {
+		fmt.Printf("No thoughts matching %q.\n", q.text)
+		return nil
+	}
+
+	if *onlyMarkers {
+		markers, err := markersForThoughts(db, thoughts)
+		if err != nil {
+			return err
+		}
+		counts := countMarkers(thoughts, markers)
+		if len(counts) == 0 {
+			fmt.Println("Matching thoughts have no markers.")
+		}
+		for _, mc := range counts {
+			fmt.Printf("#%-20s %d\n", mc.Marker, mc.Count)
+		}
+		return nil
+	}
+
+	if *context > 0 {
+		return printWithContext(db, q, thoughts, *context)
+	}
+
+	for _, t := range thoughts {
+		if *in != "" {
+			fmt.Printf("%d  %s\n", t.ID, highlightHashtags(snippet(t.Text, q.text, 30)))
+			continue
+		}
+		fmt.Println(formatThought(t))
+	}
+
+	return nil
+}
+
+// Print matches marked with > along with the n thoughts logged before and
+// after each, like grep -C. Runs of thoughts that aren't adjacent are
+// separated by --.
+func printWithContext(db *sql.DB, q thoughtQuery, matches []Thought, n int) error {
+	isMatch := make(map[int64]bool)
+	for _, t := range matches {
+		isMatch[t.ID] = true
+	}
+
+	printed := make(map[int64]bool)
+	var last int64
+	for _, m := range matches {
+		// One extra earlier thought tells whether this run joins the last
+		before, err := thoughtsAround(db, q, m, n+1, false)
+		if err != nil {
+			return err
+		}
+		after, err := thoughtsAround(db, q, m, n, true)
+		if err != nil {
+			return err
+		}
+
+		adjacent := false
+		if len(before) > n {
+			adjacent = before[0].ID == last
+			before = before[1:]
+		}
+		window := append(append(before, m), after...)
+		for _, t := range window {
+			adjacent = adjacent || printed[t.ID]
+		}
+		if last != 0 && !adjacent {
+			fmt.Println("--")
+		}
+
+		for _, t := range window {
+			if printed[t.ID] {
+				continue
+			}
+			prefix := "  "
+			if isMatch[t.ID] {
+				prefix = "> "
+			}
+			fmt.Println(prefix + strings.ReplaceAll(formatThought(t), "\n", "\n  "))
+			printed[t.ID] = true
+			last = t.ID
+		}
+	}
+	return nil
+}
+
+// Fetch up to n live thoughts logged just before or just after t, oldest
+// first, staying within the query's period
+func thoughtsAround(db *sql.DB, q thoughtQuery, t Thought, n int, later bool) ([]Thought, error) {
+	cond, order := "(timestamp < ? OR (timestamp = ? AND id < ?))", "DESC"
+	if later {
+		cond, order = "(timestamp > ? OR (timestamp = ? AND id > ?))", "ASC"
+	}
+	query := "SELECT id, timestamp, text FROM thoughts WHERE deleted_at IS NULL AND " + cond
+	args := []interface{}{t.Timestamp, t.Timestamp, t.ID}
+	if cond, condArgs := q.rangeCondition("timestamp"); cond != "" {
+		query += " AND " + cond
+		args = append(args, condArgs...)
+	}
+	query += " ORDER BY timestamp " + order + ", id " + order + " LIMIT ?"
+	args = append(args, n)
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query neighbouring thoughts: %w", err)
+	}
+	defer rows.Close()
+
+	var thoughts []Thought
+	for rows.Next() {
+		var t Thought
+		if err := rows.Scan(&t.ID, &t.Timestamp, &t.Text); err != nil {
+			return nil, fmt.Errorf("scan thought: %w", err)
+		}
+		thoughts = append(thoughts, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query neighbouring thoughts: %w", err)
+	}
+
+	if !later {
+		for i, j := 0, len(thoughts)-1; i < j; i, j = i+1, j-1 {
+			thoughts[i], thoughts[j] = thoughts[j], thoughts[i]
+		}
+	}
+	return thoughts, nil
+}
+
+// Warn on stderr when no thought carries a marker, suggesting close ones
+func warnUnknownMarker(db *sql.DB, marker string) error {
+	var exists bool
+	key, _ := splitMarker(marker)
+	if err := db.QueryRow("SELECT EXISTS(SELECT 1 FROM markers WHERE marker = ?)", normalizeMarker(key)).Scan(&exists); err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+	if exists {
+		return nil
+	}
+
+	suggestions, err := suggestMarkers(db, key, 2)
+	if err != nil {
+		return err
+	}
+	msg := fmt.Sprintf("Warning: no thoughts carry #%s", marker)
+	if len(suggestions) > 0 {
+		msg += "; " + didYouMean(suggestions)
+	}
+	fmt.Fprintln(os.Stderr, msg)
+	return nil
+}
+
+// Cut the text around the first case-insensitive match of query, keeping
+// up to radius characters on each side
+func snippet(text, query string, radius int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	runes, q := []rune(text), []rune(query)
+	pos := 0
+	for i := 0; i+len(q) <= len(runes); i++ {
+		if strings.EqualFold(string(runes[i:i+len(q)]), query) {
+			pos = i
+			break
+		}
+	}
+	start, end := max(pos-radius, 0), min(pos+len(q)+radius, len(runes))
+
+	result := string(runes[start:end])
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(runes) {
+		result += "…"
+	}
+	return result
+}
+
+// Find thoughts whose text contains the query or that carry a marker
+// containing it, labelling each hit with what it matched. A period such as
+// last:5 narrows the thoughts searched.
+func findThoughts(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	onlyIDs := fs.Bool("only-ids", false, "print only the ids of matching thoughts")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) == 0 || strings.TrimPrefix(rest[0], "#") == "" {
+		return fmt.Errorf("usage: prothought find <query> [period]")
+	}
+
+	var q thoughtQuery
+	if len(rest) > 1 {
+		if q, err = periodQuery(rest[1:]); err != nil {
+			return err
+		}
+	}
+	searched, searchedArgs := q.build()
+
+	textLike := "%" + escapeLike(rest[0]) + "%"
+	markerLike := "%" + escapeLike(normalizeMarkerArg(rest[0])) + "%"
+	rows, err := db.Query(`
+		SELECT id, timestamp, text, in_text, in_marker
+		FROM (
+			SELECT t.id, t.timestamp, t.text,
+				t.text LIKE ? ESCAPE '\' AS in_text,
+				EXISTS (SELECT 1 FROM markers m WHERE m.thought_id = t.id AND m.marker LIKE ? ESCAPE '\') AS in_marker
+			FROM (`+searched+`) t
+		)
+		WHERE in_text OR in_marker
+		ORDER BY timestamp ASC, id ASC`, append([]interface{}{textLike, markerLike}, searchedArgs...)...)
+	if err != nil {
+		return fmt.Errorf("find thoughts: %w", err)
+	}
+	defer rows.Close()
+
+	found := 0
+	for rows.Next() {
+		var t Thought
+		var inText, inMarker bool
+		if err := rows.Scan(&t.ID, &t.Timestamp, &t.Text, &inText, &inMarker); err != nil {
+			return fmt.Errorf("scan thought: %w", err)
+		}
+		found++
+		if *onlyIDs {
+			fmt.Println(t.ID)
+			continue
+		}
+		matched := "text"
+		switch {
+		case inText && inMarker:
+			matched = "both"
+		case inMarker:
+			matched = "marker"
+		}
+		fmt.Printf("%-6s  %s\n", matched, formatThought(t))
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("find thoughts: %w", err)
+	}
+
+	if found == 0 && !*onlyIDs {
+		fmt.Printf("No thoughts or markers matching %q.\n", rest[0])
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindWithPeriod(t *testing.T) {
+	db := newTestDB(t)
+	mustLog(t, db, "Ran the deploy checklist")
+	mustLog(t, db, "Rollback went fine #deploy-staging")
+	mustLog(t, db, "Lunch")
+
+	var err error
+	out := captureStdout(t, func() { err = findThoughts(db, "find", []string{"deploy"}) })
+	if err != nil {
+		t.Fatalf("find: %v", err)
+	}
+	if !strings.Contains(out, "text    ") || !strings.Contains(out, "both    ") || strings.Count(out, "\n") != 2 {
+		t.Errorf("find deploy = %q, want a text hit and one matching both", out)
+	}
+
+	// last:2 searches only the two most recent thoughts
+	out = captureStdout(t, func() { err = findThoughts(db, "find", []string{"deploy", "last:2", "--only-ids"}) })
+	if err != nil {
+		t.Fatalf("find last:2: %v", err)
+	}
+	if out != "2\n" {
+		t.Errorf("find deploy last:2 = %q, want only thought 2", out)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Serve thoughts over a small JSON API until ctx is cancelled. The API is
+// read-only unless --write is given.
+func runServer(ctx context.Context, db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addr := fs.String("addr", "127.0.0.1:8080", "address to listen on")
+	write := fs.Bool("write", false, "enable POST /thoughts")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument: %s", rest[0])
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/thoughts", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet:
+			serveThoughts(db, w, r)
+		case r.Method == http.MethodPost && *write:
+			createThought(db, w, r)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		}
+	})
+	mux.HandleFunc("/markers", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		serveMarkers(db, w)
+	})
+
+	srv := &http.Server{Addr: *addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	mode := "read-only"
+	if *write {
+		mode = "read-write"
+	}
+	fmt.Printf("Serving %s API on http://%s\n", mode, *addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+	return nil
+}
+
+// GET /thoughts?period=...&marker=...
+func serveThoughts(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	var periodArgs []string
+	if p := r.URL.Query().Get("period"); p != "" {
+		periodArgs = []string{p}
+	}
+	marker := strings.TrimPrefix(r.URL.Query().Get("marker"), "#")
+
+	thoughts, err := thoughtsForPeriod(db, periodArgs, marker)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	markers, err := markersForThoughts(db, thoughts)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	objects := make([]orderedObject, len(thoughts))
+	for i, t := range thoughts {
+		tags := markers[t.ID]
+		if tags == nil {
+			tags = []string{}
+		}
+		objects[i] = exportRecord{Thought: t, Markers: tags}.object(exportFields)
+	}
+	writeJSONResponse(w, http.StatusOK, objects)
+}
+
+// GET /markers
+func serveMarkers(db *sql.DB, w http.ResponseWriter) {
+	counts, err := countMarkersForQuery(db, thoughtQuery{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	type markerJSON struct {
+		Marker string `json:"marker"`
+		Count  int    `json:"count"`
+	}
+	result := make([]markerJSON, len(counts))
+	for i, mc := range counts {
+		result[i] = markerJSON{Marker: mc.Marker, Count: mc.Count}
+	}
+	writeJSONResponse(w, http.StatusOK, result)
+}
+
+// POST /thoughts with a body of {"text": "..."}
+func createThought(db *sql.DB, w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		Text string `json:"text"`
+	}
+	if err := json.NewDecoder(io.LimitReader(r.Body, 1<<20)).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid JSON body")
+		return
+	}
+	text := strings.TrimSpace(body.Text)
+	if text == "" {
+		writeError(w, http.StatusBadRequest, "text is required")
+		return
+	}
+
+	t, hashtags, _, err := saveThought(db, text, addOptions{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if hashtags == nil {
+		hashtags = []string{}
+	}
+	writeJSONResponse(w, http.StatusCreated, exportRecord{Thought: t, Markers: hashtags}.object(exportFields))
+}
+
+// Write v as a JSON response
+func writeJSONResponse(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// Write an error as a JSON response
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSONResponse(w, status, map[string]string{"error": msg})
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Print a single thought in full with its timestamp and markers
+func showThought(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "print the thought as a JSON object")
+	asMD := fs.Bool("md", false, "print the thought as Markdown")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 1 {
+		return fmt.Errorf("usage: prothought show <id> [--json | --md]")
+	}
+	if *asJSON && *asMD {
+		return fmt.Errorf("--json and --md cannot be used together")
+	}
+	id, err := parseThoughtID(rest[0])
+	if err != nil {
+		return err
+	}
+
+	var t Thought
+	var deletedAt sql.NullString
+	err = db.QueryRow("SELECT id, timestamp, text, deleted_at FROM thoughts WHERE id = ?", id).
+		Scan(&t.ID, &t.Timestamp, &t.Text, &deletedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no thought with id %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("query thought: %w", err)
+	}
+	if deletedAt.Valid {
+		return fmt.Errorf("thought %d is in the trash; bring it back with: prothought restore %d", id, id)
+	}
+
+	markers, err := markersForThoughts(db, []Thought{t})
+	if err != nil {
+		return err
+	}
+	tags := markers[t.ID]
+	if tags == nil {
+		tags = []string{}
+	}
+
+	switch {
+	case *asJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(exportRecord{Thought: t, Markers: tags}.object(exportFields)); err != nil {
+			return fmt.Errorf("encode json: %w", err)
+		}
+	case *asMD:
+		fmt.Printf("## %s\n\n%s\n", t.Timestamp, strings.TrimSpace(t.Text))
+		if len(tags) > 0 {
+			fmt.Printf("\n**Markers:** %s\n", joinMarkers(tags))
+		}
+	default:
+		fmt.Printf("Thought %d\n", t.ID)
+		fmt.Printf("Logged:  %s\n", displayTime(t.Timestamp))
+		if len(tags) > 0 {
+			fmt.Printf("Markers: %s\n", highlightHashtags(joinMarkers(tags)))
+		}
+		fmt.Printf("\n%s\n", highlightHashtags(strings.TrimSpace(t.Text)))
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Handle the tmpl save/use/list subcommands for reusable thought templates
+func runSnippets(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: prothought tmpl save <name> <text> | use <name> | list")
+	}
+
+	switch args[0] {
+	case "save":
+		if len(args) < 3 {
+			return fmt.Errorf("usage: prothought tmpl save <name> <text>")
+		}
+		name := args[1]
+		// Allow "\n" in the shell argument to stand for a line break
+		body := strings.ReplaceAll(strings.Join(args[2:], " "), `\n`, "\n")
+		if _, err := db.Exec(`
+			INSERT INTO templates (name, body) VALUES (?, ?)
+			ON CONFLICT(name) DO UPDATE SET body = excluded.body`, name, body); err != nil {
+			return fmt.Errorf("save template: %w", err)
+		}
+		fmt.Printf("Saved template %s.\n", name)
+
+	case "use":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: prothought tmpl use <name>")
+		}
+		var body string
+		err := db.QueryRow("SELECT body FROM templates WHERE name = ?", args[1]).Scan(&body)
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("no template named %s", args[1])
+		}
+		if err != nil {
+			return fmt.Errorf("query template: %w", err)
+		}
+
+		text, err := editText(body)
+		if err != nil {
+			return err
+		}
+		text = strings.TrimSpace(text)
+		if text == "" || text == strings.TrimSpace(body) {
+			fmt.Println("Template left unchanged, nothing saved.")
+			return nil
+		}
+		return logThought(db, text, addOptions{})
+
+	case "list":
+		rows, err := db.Query("SELECT name, body FROM templates ORDER BY name")
+		if err != nil {
+			return fmt.Errorf("query templates: %w", err)
+		}
+		defer rows.Close()
+
+		count := 0
+		for rows.Next() {
+			var name, body string
+			if err := rows.Scan(&name, &body); err != nil {
+				return fmt.Errorf("scan template: %w", err)
+			}
+			fmt.Printf("%-15s %s\n", name, strings.ReplaceAll(body, "\n", `\n`))
+			count++
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("query templates: %w", err)
+		}
+		if count == 0 {
+			fmt.Println("No templates saved.")
+		}
+
+	default:
+		return fmt.Errorf("unknown tmpl subcommand: %s", args[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// strikeStats counts thoughts and how many of them were struck
+type strikeStats struct {
+	Thoughts int     `json:"thoughts"`
+	Struck   int     `json:"struck"`
+	NvmRate  float64 `json:"nvm_rate"`
+}
+
+// periodStats is the shape printed by stats --json
+type periodStats struct {
+	Start    string      `json:"start"`
+	End      string      `json:"end"`
+	Markers  int         `json:"markers"`
+	Current  strikeStats `json:"current"`
+	Previous strikeStats `json:"previous"`
+}
+
+// Count the distinct markers on live thoughts between two timestamps
+const statsMarkersQuery = `SELECT COUNT(DISTINCT m.marker)
+FROM markers m
+INNER JOIN thoughts t ON t.id = m.thought_id
+WHERE substr(t.timestamp, 1, 19) BETWEEN ? AND ?
+  AND t.deleted_at IS NULL`
+
+// Count thoughts and struck thoughts between two timestamps
+func countStrikes(db *sql.DB, start, end string) (strikeStats, error) {
+	thoughts, err := queryThoughts(db, thoughtQuery{start: start, end: end})
+	if err != nil {
+		return strikeStats{}, err
+	}
+
+	s := strikeStats{Thoughts: len(thoughts)}
+	for _, t := range thoughts {
+		if isStruck(t.Text) {
+			s.Struck++
+		}
+	}
+	if s.Thoughts > 0 {
+		s.NvmRate = float64(s.Struck) / float64(s.Thoughts)
+	}
+	return s, nil
+}
+
+// Print statistics for a period, comparing the nvm rate with the period of
+// the same length just before it
+func showStats(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "print statistics as JSON")
+	showSQL := fs.Bool("print-sql", false, "print the SQL and its parameters to stderr instead of running it")
+	periodArgs, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	startTS, endTS, err := parsePeriod(periodArgs)
+	if err != nil {
+		return err
+	}
+	start, _ := time.ParseInLocation(timestampFormat, startTS, time.Local)
+	end, _ := time.ParseInLocation(timestampFormat, endTS, time.Local)
+	length := end.Sub(start) + time.Second
+	prevStart := start.Add(-length).Format(timestampFormat)
+	prevEnd := start.Add(-time.Second).Format(timestampFormat)
+
+	if *showSQL {
+		printSQL(thoughtQuery{start: startTS, end: endTS}.build())
+		fmt.Fprintln(os.Stderr)
+		printSQL(thoughtQuery{start: prevStart, end: prevEnd}.build())
+		fmt.Fprintln(os.Stderr)
+		printSQL(statsMarkersQuery, []interface{}{startTS, endTS})
+		return nil
+	}
+
+	stats := periodStats{Start: startTS, End: endTS}
+	if stats.Current, err = countStrikes(db, startTS, endTS); err != nil {
+		return err
+	}
+	if stats.Previous, err = countStrikes(db, prevStart, prevEnd); err != nil {
+		return err
+	}
+
+	if err := db.QueryRow(statsMarkersQuery, startTS, endTS).Scan(&stats.Markers); err != nil {
+		return fmt.Errorf("query markers: %w", err)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(stats); err != nil {
+			return fmt.Errorf("encode json: %w", err)
+		}
+		return nil
+	}
+
+	trend := "no thoughts the period before"
+	if stats.Previous.Thoughts > 0 {
+		diff := (stats.Current.NvmRate - stats.Previous.NvmRate) * 100
+		switch {
+		case diff > 0:
+			trend = fmt.Sprintf("up %.1f points from %.1f%%", diff, stats.Previous.NvmRate*100)
+		case diff < 0:
+			trend = fmt.Sprintf("down %.1f points from %.1f%%", -diff, stats.Previous.NvmRate*100)
+		default:
+			trend = "unchanged from the period before"
+		}
+	}
+
+	fmt.Printf("Period:    %s .. %s\n", startTS, endTS)
+	fmt.Printf("Thoughts:  %d\n", stats.Current.Thoughts)
+	fmt.Printf("Markers:   %d distinct\n", stats.Markers)
+	fmt.Printf("Nvm rate:  %.1f%% (%d of %d struck; %s)\n",
+		stats.Current.NvmRate*100, stats.Current.Struck, stats.Current.Thoughts, trend)
+	return nil
+}
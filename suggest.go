@@ -0,0 +1,90 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Compute the Levenshtein edit distance between two strings
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+// Load every distinct marker in use
+func distinctMarkers(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SELECT DISTINCT marker FROM markers ORDER BY marker")
+	if err != nil {
+		return nil, fmt.Errorf("query markers: %w", err)
+	}
+	defer rows.Close()
+
+	var markers []string
+	for rows.Next() {
+		var m string
+		if err := rows.Scan(&m); err != nil {
+			return nil, fmt.Errorf("scan marker: %w", err)
+		}
+		markers = append(markers, m)
+	}
+
+	return markers, rows.Err()
+}
+
+// Suggest existing markers close to an unknown one. Returns nil when the
+// marker exists or nothing is within maxDistance edits.
+func suggestMarkers(db *sql.DB, marker string, maxDistance int) ([]string, error) {
+	markers, err := distinctMarkers(db)
+	if err != nil {
+		return nil, err
+	}
+
+	marker = normalizeMarker(marker)
+	best := maxDistance + 1
+	var suggestions []string
+	for _, m := range markers {
+		if m == marker {
+			return nil, nil
+		}
+		d := levenshtein(marker, m)
+		if d < best {
+			best = d
+			suggestions = []string{m}
+		} else if d == best {
+			suggestions = append(suggestions, m)
+		}
+	}
+
+	if best > maxDistance {
+		return nil, nil
+	}
+	return suggestions, nil
+}
+
+// Format marker suggestions as a "did you mean" hint
+func didYouMean(suggestions []string) string {
+	tags := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		tags[i] = "#" + s
+	}
+	return "did you mean " + strings.Join(tags, " or ") + "?"
+}
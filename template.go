@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/template"
+)
+
+// Load an output template from inline text or a template file
+func loadTemplate(inline, file string) (*template.Template, error) {
+	if inline != "" && file != "" {
+		return nil, fmt.Errorf("--template and --template-file cannot be used together")
+	}
+
+	if file == "" {
+		// Inline templates describe a single line per thought
+		tmpl, err := template.New("inline").Parse(inline + "\n")
+		if err != nil {
+			return nil, fmt.Errorf("parse template: %w", err)
+		}
+		return tmpl, nil
+	}
+
+	content, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("template file not found: %s", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read template file: %w", err)
+	}
+
+	tmpl, err := template.New(filepath.Base(file)).Parse(string(content))
+	if err != nil {
+		return nil, fmt.Errorf("parse template file: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Render thoughts through a template. The main template is executed once per
+// thought; optional "header" and "footer" templates receive the whole list.
+func renderTemplate(w io.Writer, tmpl *template.Template, thoughts []Thought) error {
+	if header := tmpl.Lookup("header"); header != nil {
+		if err := header.Execute(w, thoughts); err != nil {
+			return fmt.Errorf("render header: %w", err)
+		}
+	}
+
+	for _, t := range thoughts {
+		if err := tmpl.Execute(w, t); err != nil {
+			return fmt.Errorf("render thought: %w", err)
+		}
+	}
+
+	if footer := tmpl.Lookup("footer"); footer != nil {
+		if err := footer.Execute(w, thoughts); err != nil {
+			return fmt.Errorf("render footer: %w", err)
+		}
+	}
+
+	return nil
+}
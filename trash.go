@@ -0,0 +1,201 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Parse a thought id argument
+func parseThoughtID(arg string) (int64, error) {
+	id, err := strconv.ParseInt(arg, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid thought id: %s", arg)
+	}
+	return id, nil
+}
+
+// Move a thought to the trash
+func trashThought(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: prothought delete <id>")
+	}
+	id, err := parseThoughtID(args[0])
+	if err != nil {
+		return err
+	}
+
+	var ts string
+	var deletedAt sql.NullString
+	err = db.QueryRow("SELECT timestamp, deleted_at FROM thoughts WHERE id = ?", id).Scan(&ts, &deletedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no thought with id %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("query thought: %w", err)
+	}
+	if deletedAt.Valid {
+		fmt.Printf("Thought %d is already in the trash.\n", id)
+		return nil
+	}
+	ok, err := confirmProtected(db, "delete", "id = ?", id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	now := time.Now().Format(timestampFormat)
+	if _, err := db.Exec("UPDATE thoughts SET deleted_at = ? WHERE id = ?", now, id); err != nil {
+		return fmt.Errorf("update thought: %w", err)
+	}
+
+	fmt.Printf("Moved thought %d from %s to the trash. Undo with: prothought restore %d\n", id, ts, id)
+	return nil
+}
+
+// Bring a thought back from the trash
+func restoreThought(db *sql.DB, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: prothought restore <id>")
+	}
+	id, err := parseThoughtID(args[0])
+	if err != nil {
+		return err
+	}
+
+	var ts string
+	var deletedAt sql.NullString
+	err = db.QueryRow("SELECT timestamp, deleted_at FROM thoughts WHERE id = ?", id).Scan(&ts, &deletedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("no thought with id %d", id)
+	}
+	if err != nil {
+		return fmt.Errorf("query thought: %w", err)
+	}
+	if !deletedAt.Valid {
+		fmt.Printf("Thought %d is not in the trash.\n", id)
+		return nil
+	}
+
+	if _, err := db.Exec("UPDATE thoughts SET deleted_at = NULL WHERE id = ?", id); err != nil {
+		return fmt.Errorf("update thought: %w", err)
+	}
+
+	fmt.Printf("Restored thought %d from %s.\n", id, ts)
+	return nil
+}
+
+// List the thoughts in the trash
+func listTrash(db *sql.DB) error {
+	rows, err := db.Query(`
+		SELECT id, timestamp, text, deleted_at
+		FROM thoughts
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at ASC, id ASC`)
+	if err != nil {
+		return fmt.Errorf("query trash: %w", err)
+	}
+	defer rows.Close()
+
+	count := 0
+	for rows.Next() {
+		var t Thought
+		var deletedAt string
+		if err := rows.Scan(&t.ID, &t.Timestamp, &t.Text, &deletedAt); err != nil {
+			return fmt.Errorf("scan thought: %w", err)
+		}
+		fmt.Printf("%d  %s (deleted %s)\n", t.ID, formatThought(t), displayTime(deletedAt))
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query trash: %w", err)
+	}
+
+	if count == 0 {
+		fmt.Println("Trash is empty.")
+	}
+	return nil
+}
+
+// Permanently remove every thought in the trash
+func emptyTrash(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	yes := fs.Bool("yes", false, "never ask for confirmation")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(rest) > 0 {
+		return fmt.Errorf("unexpected argument: %s", rest[0])
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM thoughts WHERE deleted_at IS NOT NULL").Scan(&count); err != nil {
+		return fmt.Errorf("query trash count: %w", err)
+	}
+	if count == 0 {
+		fmt.Println("Trash is empty.")
+		return nil
+	}
+
+	ok, err := confirmBulk(count, fmt.Sprintf("permanently delete %d thought(s) from the trash", count), *yes)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+	ok, err = confirmProtected(db, "permanently delete", "deleted_at IS NOT NULL")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		fmt.Println("Aborted.")
+		return nil
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := deleteThoughtsWhere(tx, "deleted_at IS NOT NULL"); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	fmt.Printf("Permanently deleted %d thought(s).\n", count)
+	return nil
+}
+
+// Permanently delete the thoughts matching cond along with their markers
+// and attachments. Foreign keys are not enforced, so dependent rows are
+// removed explicitly.
+func deleteThoughtsWhere(tx *sql.Tx, cond string, args ...interface{}) (int64, error) {
+	for _, table := range []string{"markers", "attachments", "thought_history"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+`
+			WHERE thought_id IN (SELECT id FROM thoughts WHERE `+cond+`)`, args...); err != nil {
+			return 0, fmt.Errorf("delete %s: %w", table, err)
+		}
+	}
+	result, err := tx.Exec("DELETE FROM thoughts WHERE "+cond, args...)
+	if err != nil {
+		return 0, fmt.Errorf("delete thoughts: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("count deleted thoughts: %w", err)
+	}
+	return n, nil
+}
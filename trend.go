@@ -0,0 +1,196 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Render counts as a one-line sparkline
+func sparkline(counts []int) string {
+	maxCount := 0
+	for _, c := range counts {
+		maxCount = max(maxCount, c)
+	}
+
+	var b strings.Builder
+	for _, c := range counts {
+		if maxCount == 0 {
+			b.WriteRune(sparkBlocks[0])
+			continue
+		}
+		b.WriteRune(sparkBlocks[c*(len(sparkBlocks)-1)/maxCount])
+	}
+	return b.String()
+}
+
+// Show how often a marker was used per day or week over a period
+func showTrend(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	weekly := fs.Bool("weekly", false, "group counts per week instead of per day")
+	rest, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+
+	periodArgs, marker := parseArgsWithMarker(rest)
+	if marker == "" {
+		return fmt.Errorf("usage: prothought trend #marker [period] [--weekly]")
+	}
+	if len(periodArgs) == 0 {
+		periodArgs = []string{"lastmonth"}
+	}
+	startTS, endTS, err := parsePeriod(periodArgs)
+	if err != nil {
+		return err
+	}
+
+	// A key=value marker must match its value too; a plain key matches any value
+	key, value := splitMarker(marker)
+	valueCond := ""
+	queryArgs := []interface{}{normalizeMarker(key)}
+	if value != "" {
+		valueCond = "AND m.value = ?"
+		queryArgs = append(queryArgs, normalizeMarker(value))
+	}
+	rows, err := db.Query(`
+		SELECT substr(t.timestamp, 1, 10) AS day, COUNT(DISTINCT t.id)
+		FROM thoughts t
+		INNER JOIN markers m ON t.id = m.thought_id
+		WHERE m.marker = ? `+valueCond+`
+		  AND substr(t.timestamp, 1, 19) BETWEEN ? AND ?
+		  AND t.deleted_at IS NULL
+		GROUP BY day`,
+		append(queryArgs, startTS, endTS)...)
+	if err != nil {
+		return fmt.Errorf("query trend: %w", err)
+	}
+	defer rows.Close()
+
+	perDay := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return fmt.Errorf("scan trend: %w", err)
+		}
+		perDay[day] = count
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query trend: %w", err)
+	}
+
+	// Bucket the range by day or by week, starting from the first day
+	start, _ := time.ParseInLocation(timestampFormat, startTS, time.Local)
+	end, _ := time.ParseInLocation(timestampFormat, endTS, time.Local)
+	step := 1
+	if *weekly {
+		step = 7
+	}
+
+	var labels []string
+	var counts []int
+	total := 0
+	for d := start; !d.After(end); d = d.AddDate(0, 0, step) {
+		count := 0
+		for i := 0; i < step; i++ {
+			count += perDay[d.AddDate(0, 0, i).Format("2006-01-02")]
+		}
+		labels = append(labels, d.Format("2006-01-02"))
+		counts = append(counts, count)
+		total += count
+	}
+
+	fmt.Printf("#%s %s .. %s\n", marker, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	fmt.Printf("%s  total %d\n\n", sparkline(counts), total)
+
+	unit := "day"
+	if *weekly {
+		unit = "week of"
+	}
+	for i, label := range labels {
+		line := fmt.Sprintf("%-8s %s  %3d %s", unit, label, counts[i], strings.Repeat("█", counts[i]))
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+
+	return nil
+}
+
+// Print the number of thoughts logged on each day of a period, including
+// days without any, as a table or CSV for external charting
+func showCountPerDay(db *sql.DB, cmd string, args []string) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	format := fs.String("format", "table", "output format: table or csv")
+	periodArgs, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if *format != "table" && *format != "csv" {
+		return fmt.Errorf("unsupported format: %s", *format)
+	}
+
+	if len(periodArgs) == 0 {
+		periodArgs = []string{"lastmonth"}
+	}
+	startTS, endTS, err := parsePeriod(periodArgs)
+	if err != nil {
+		return err
+	}
+
+	rows, err := db.Query(`
+		SELECT date(timestamp) AS day, COUNT(*)
+		FROM thoughts
+		WHERE substr(timestamp, 1, 19) BETWEEN ? AND ?
+		  AND deleted_at IS NULL
+		GROUP BY date(timestamp)`, startTS, endTS)
+	if err != nil {
+		return fmt.Errorf("query counts: %w", err)
+	}
+	defer rows.Close()
+
+	perDay := make(map[string]int)
+	for rows.Next() {
+		var day string
+		var count int
+		if err := rows.Scan(&day, &count); err != nil {
+			return fmt.Errorf("scan count: %w", err)
+		}
+		perDay[day] = count
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("query counts: %w", err)
+	}
+
+	start, _ := time.ParseInLocation(timestampFormat, startTS, time.Local)
+	end, _ := time.ParseInLocation(timestampFormat, endTS, time.Local)
+
+	if *format == "csv" {
+		cw := csv.NewWriter(os.Stdout)
+		cw.Write([]string{"date", "count"})
+		for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+			day := d.Format("2006-01-02")
+			cw.Write([]string{day, strconv.Itoa(perDay[day])})
+		}
+		cw.Flush()
+		if err := cw.Error(); err != nil {
+			return fmt.Errorf("write csv: %w", err)
+		}
+		return nil
+	}
+
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		day := d.Format("2006-01-02")
+		fmt.Printf("%s  %d\n", day, perDay[day])
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// verbosity is raised by -v (diagnostics) and -vv (also SQL timing)
+var verbosity int
+
+// Print a diagnostic to stderr when running at least this verbose
+func debugf(level int, format string, args ...interface{}) {
+	if verbosity >= level {
+		fmt.Fprintf(os.Stderr, "debug: "+format+"\n", args...)
+	}
+}
+
+// Collapse a multi-line SQL statement onto one line for diagnostics
+func oneLineSQL(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
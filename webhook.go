@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// How long to wait for the webhook before giving up
+const webhookTimeout = 3 * time.Second
+
+// POST a newly logged thought as JSON to the configured webhook
+func notifyWebhook(url string, t Thought, markers []string) error {
+	if markers == nil {
+		markers = []string{}
+	}
+	body, err := json.Marshal(exportRecord{Thought: t, Markers: markers}.object(exportFields))
+	if err != nil {
+		return fmt.Errorf("encode thought: %w", err)
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("post webhook: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+)
+
+type diffOp int
+
+const (
+	diffEqual diffOp = iota
+	diffDelete
+	diffInsert
+)
+
+// A run of words that were kept, removed or added
+type diffChunk struct {
+	op    diffOp
+	words []string
+}
+
+// Diff two texts word by word using the longest common subsequence
+func diffWords(old, new string) []diffChunk {
+	a, b := strings.Fields(old), strings.Fields(new)
+
+	// lcs[i][j] is the common subsequence length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var chunks []diffChunk
+	add := func(op diffOp, word string) {
+		if n := len(chunks); n > 0 && chunks[n-1].op == op {
+			chunks[n-1].words = append(chunks[n-1].words, word)
+			return
+		}
+		chunks = append(chunks, diffChunk{op: op, words: []string{word}})
+	}
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			add(diffEqual, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(diffDelete, a[i])
+			i++
+		default:
+			add(diffInsert, b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		add(diffDelete, a[i])
+	}
+	for ; j < len(b); j++ {
+		add(diffInsert, b[j])
+	}
+	return chunks
+}
+
+// Render a word diff, coloring removals red and additions green, or
+// marking them [-like this-] and {+like this+} when color is off
+func formatWordDiff(chunks []diffChunk) string {
+	color := useColor()
+	parts := make([]string, 0, len(chunks))
+	for _, c := range chunks {
+		text := strings.Join(c.words, " ")
+		switch {
+		case c.op == diffEqual:
+			parts = append(parts, text)
+		case color && c.op == diffDelete:
+			parts = append(parts, colorRed+colorStrike+text+colorReset)
+		case color:
+			parts = append(parts, colorGreen+text+colorReset)
+		case c.op == diffDelete:
+			parts = append(parts, "[-"+text+"-]")
+		default:
+			parts = append(parts, "{+"+text+"+}")
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// Determine the width to wrap output at. Returns 0 (no wrapping) when
+// stdout is not a terminal or plain output was requested.
+func wrapWidth() int {
+	if cfg.enabled("plain") || !isTerminal(os.Stdout) {
+		return 0
+	}
+	if w, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && w > 0 {
+		return w
+	}
+	if w, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && w > 0 {
+		return w
+	}
+	return 0
+}
+
+// Word-wrap text so each line fits in width, leaving room for a first-line
+// prefix of indent characters. Continuation lines are indented to align
+// under the text. A width of 0 disables wrapping.
+func wrapText(text string, width, indent int) []string {
+	avail := width - indent
+	if width <= 0 || avail < 20 {
+		return strings.Split(text, "\n")
+	}
+
+	pad := strings.Repeat(" ", indent)
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+
+		line, lineLen := words[0], utf8.RuneCountInString(words[0])
+		for _, word := range words[1:] {
+			wordLen := utf8.RuneCountInString(word)
+			if lineLen+1+wordLen > avail {
+				lines = append(lines, line)
+				line, lineLen = word, wordLen
+				continue
+			}
+			line += " " + word
+			lineLen += 1 + wordLen
+		}
+		lines = append(lines, line)
+	}
+
+	for i := 1; i < len(lines); i++ {
+		lines[i] = pad + lines[i]
+	}
+	return lines
+}
+
+// Format a thought as "[timestamp] text" for the terminal, wrapping long
+// text under the timestamp prefix
+func formatThought(t Thought) string {
+	ts := displayTime(t.Timestamp)
+	lines := wrapText(t.Text, wrapWidth(), utf8.RuneCountInString(ts)+3)
+	for i := range lines {
+		lines[i] = highlightHashtags(lines[i])
+	}
+	return "[" + colorize(colorDim, ts) + "] " + strings.Join(lines, "\n")
+}